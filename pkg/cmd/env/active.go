@@ -6,6 +6,7 @@ import (
 	"github.com/coreeng/core-platform/pkg/environment"
 	"github.com/coreeng/corectl/pkg/cmdutil/config"
 	"github.com/coreeng/corectl/pkg/cmdutil/configpath"
+	"github.com/coreeng/corectl/pkg/cmdutil/output"
 	"github.com/coreeng/corectl/pkg/cmdutil/userio"
 	corectlenv "github.com/coreeng/corectl/pkg/env"
 	"github.com/spf13/cobra"
@@ -17,6 +18,9 @@ type ActiveOpt struct {
 	All                bool
 	Restricted         bool
 	Quiet              bool
+	Output             string
+	// Ports are the ports assigned to the proxies of environments
+	Ports map[string]int
 }
 
 func activeCmd(cfg *config.Config) *cobra.Command {
@@ -24,14 +28,37 @@ func activeCmd(cfg *config.Config) *cobra.Command {
 	activeCmd := &cobra.Command{
 		Use:   "active <environment>",
 		Short: "Show active proxies for environments",
-		Long:  `This command allows you to list the active proxies for environments.`,
-		Args:  cobra.MinimumNArgs(0),
+		Long: `This command allows you to list the active proxies for environments along with their health, and the forwards to services running in them.
+
+With -o json or -o yaml a list of environments is printed for tools to read, with these fields:
+
+  name                       name of the environment
+  tier                       tier of the environment, e.g. dev or prod
+  cloud                      cloud platform of the environment, gcp or aws
+  id                         GCP project ID or AWS account ID
+  region                     region of the cluster
+  domains.ingress            domains of the ingresses of the environment
+  domains.internalServices   domain of the internal services of the environment
+  port                       local port assigned to the proxy of the environment, absent when none is
+  proxy.address              address the proxy listens on, proxy is absent when no proxy is running
+  proxy.pid                  pid of the process serving the proxy
+  proxy.health.healthy       whether the last probe of the tunnel succeeded
+  proxy.health.lastSuccess   time of the last successful probe
+  proxy.health.lastError     error of the last failed probe
+  proxy.health.reconnects    number of times the tunnel was re-opened
+  forwards                   forwards to services of the environment, with target, address, pid and health
+
+Fields are only ever added, never renamed or removed.`,
+		Args: cobra.MinimumNArgs(0),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			var (
 				err                   error
 				availableEnvironments []environment.Environment
 			)
 			cmd.SilenceUsage = true
+			if err := output.Validate(opts.Output, output.Table, output.JSON, output.YAML); err != nil {
+				return err
+			}
 
 			nonInteractive, err := cmd.Flags().GetBool("non-interactive")
 			if err != nil {
@@ -73,6 +100,12 @@ func activeCmd(cfg *config.Config) *cobra.Command {
 				opts.Restricted = true
 			}
 
+			registry, err := corectlenv.LoadPortRegistry(pinnedPorts(cfg))
+			if err != nil {
+				return err
+			}
+			opts.Ports = registry.Ports()
+
 			return active(opts, availableEnvironments)
 		},
 	}
@@ -91,6 +124,8 @@ func activeCmd(cfg *config.Config) *cobra.Command {
 		"Don't print output just set the exitcode",
 	)
 
+	output.RegisterFlag(activeCmd, &opts.Output, output.Table, output.JSON, output.YAML)
+
 	config.RegisterBoolParameterAsFlag(
 		&cfg.Repositories.AllowDirty,
 		activeCmd.Flags(),
@@ -99,26 +134,53 @@ func activeCmd(cfg *config.Config) *cobra.Command {
 }
 
 func active(opts ActiveOpt, environments []environment.Environment) error {
-	// Call getProxyPids to get pids
-	pids, err := corectlenv.GetProxyPids(environments)
-	_ = err
+	proxies := corectlenv.GetActiveProxies(environments)
+	activeForwards := make(map[string][]corectlenv.ForwardInfo)
+	for _, env := range environments {
+		activeForwards[env.Environment] = corectlenv.GetActiveForwards(env.Environment)
+	}
 
 	table := corectlenv.NewTable(opts.Streams, true)
+	var envs []corectlenv.EnvironmentOutput
 	allHaveProxies := true
 	for _, env := range environments {
-		proxy, pid := "-", "-"
-		if proc, exists := pids[env.Environment]; exists {
-			proxy, pid = fmt.Sprintf("localhost:%d", proc.Port), fmt.Sprintf("%d", proc.Pid)
+		var info *corectlenv.ProxyInfo
+		if proxy, exists := proxies[env.Environment]; exists {
+			info = &proxy
 		} else {
 			allHaveProxies = false
 			if !opts.All {
 				continue
 			}
 		}
-		table.AppendEnv(env, proxy, pid)
+		table.AppendEnvWithProxy(env, info)
+		envOutput := corectlenv.NewEnvironmentOutput(env, info, activeForwards[env.Environment])
+		envOutput.Port = opts.Ports[env.Environment]
+		envs = append(envs, envOutput)
+	}
+	forwards := corectlenv.NewForwardTable(opts.Streams)
+	hasForwards := false
+	for _, env := range environments {
+		for _, forward := range activeForwards[env.Environment] {
+			forwards.AppendForward(forward)
+			hasForwards = true
+		}
 	}
 	if !opts.Quiet {
-		table.Render()
+		if opts.Output != output.Table {
+			if envs == nil {
+				envs = []corectlenv.EnvironmentOutput{}
+			}
+			if err := output.Encode(opts.Streams.GetOutput(), opts.Output, envs); err != nil {
+				return err
+			}
+		} else {
+			table.Render()
+			if hasForwards {
+				_, _ = fmt.Fprintln(opts.Streams.GetOutput())
+				forwards.Render()
+			}
+		}
 	}
 	if opts.Restricted && !allHaveProxies {
 		return fmt.Errorf("not all specified environments have active proxies")
@@ -7,9 +7,12 @@ import (
 	"fmt"
 	"github.com/coreeng/corectl/pkg/cmdutil/configpath"
 	"os"
+	"path/filepath"
+	"slices"
 	"strings"
 
 	"github.com/coreeng/core-platform/pkg/environment"
+	"github.com/coreeng/core-platform/pkg/tenant"
 	"github.com/coreeng/corectl/pkg/cmdutil/config"
 	"github.com/coreeng/corectl/pkg/cmdutil/userio"
 	"github.com/coreeng/corectl/pkg/command"
@@ -37,9 +40,9 @@ func findEnvironmentByName(name string, environments []environment.Environment)
 	return nil, fmt.Errorf("could not find environment: %s", name)
 }
 
-func connectCmd(cfg *config.Config) *cobra.Command {
-	opts := corectlenv.EnvConnectOpts{
-		SkipTunnel: true,
+// newConnectOpts returns the options env connect starts from, before its flags are parsed.
+func newConnectOpts() corectlenv.EnvConnectOpts {
+	return corectlenv.EnvConnectOpts{
 		SilentExec: command.NewCommander(
 			command.WithStdout(&bytes.Buffer{}),
 			command.WithStderr(&bytes.Buffer{}),
@@ -49,11 +52,59 @@ func connectCmd(cfg *config.Config) *cobra.Command {
 			command.WithStderr(os.Stderr),
 		),
 	}
+}
+
+func connectCmd(cfg *config.Config) *cobra.Command {
+	opts := newConnectOpts()
+	var allFromTenant string
+	var bastion corectlenv.Bastion
 	connectCmd := &cobra.Command{
-		Use:   "connect <environment>",
-		Short: "Connect to an environment",
-		Long:  `This command allows you to connect to a specified environment.`,
-		Args:  cobra.MinimumNArgs(1),
+		Use:   "connect <environment>...",
+		Short: "Connect to one or more environments",
+		Long: `This command allows you to connect to a specified environment.
+
+When several environments are given, or all the environments of a tenant with --all-from-tenant,
+a single process serves a proxy for each of them on the port assigned to the environment.
+
+Each environment is assigned a stable port, recorded in ` + corectlenv.PortsFile + ` in the corectl home, and replaced when
+another process uses it. Pin the port of an environment in the config with environments.<name>.proxy-port,
+connecting fails when a pinned port is in use. See the assigned ports with env list.
+
+The --bastion-* flags override the bastion of a single environment, set environments.<name>.bastion in the config
+to override the bastion of each environment when connecting to several.
+
+With --metrics-port the process serving the proxies exposes their metrics in the Prometheus format at
+http://localhost:<port>/metrics: active and total connections, tunnel dial latency and failures, and bytes
+transferred, labelled by environment.
+
+With --background the proxies are served by a detached process, whose output goes to
+` + filepath.Join("proxies", corectlenv.BackgroundLogFile) + ` in the corectl home.
+
+With --skip-tunnel no proxy is started, the kubeconfig entry is written without a proxy url for clusters
+reachable without the tunnel, and the command run after -- runs straight away.
+
+When interrupted, terminated or disconnected, a proxy stops accepting connections and lets the active ones finish
+for a while before closing them, reporting an error if it had to. When signalled, corectl exits with 128 plus
+the signal number, as shells do.
+
+With --isolated-kubeconfig, or kubernetes.isolated-kubeconfig set in the config, the cluster credentials
+are written to a kubeconfig file of the environment in the corectl home instead of the global kubeconfig,
+so other terminals are not retargeted. The command run after -- gets KUBECONFIG set to that file.
+
+Before connecting, the caller is checked to have the IAM permissions needed on the project of GCP environments,
+see env validate.
+
+Connecting to a production environment has to be confirmed, or --i-know-this-is-prod passed when running
+non-interactively. Every command run after -- is recorded in the audit log in the corectl home.
+
+The command run after -- is attached to the terminal, so interactive tools like k9s work,
+and corectl exits with its exit code.`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if allFromTenant == "" {
+				return cobra.MinimumNArgs(1)(cmd, args)
+			}
+			return nil
+		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			var (
 				err                   error
@@ -74,13 +125,23 @@ func connectCmd(cfg *config.Config) *cobra.Command {
 				!nonInteractive,
 			)
 
-			repoParams := []config.Parameter[string]{cfg.Repositories.CPlatform}
-			err = config.Update(cfg.GitHub.Token.Value, opts.Streams, cfg.Repositories.AllowDirty.Value, repoParams)
-			if err != nil {
-				return fmt.Errorf("failed to update config repos: %w", err)
+			// The background child serves what its parent resolved, from the repositories it updated
+			if !corectlenv.IsConnectChild(opts) {
+				repoParams := []config.Parameter[string]{cfg.Repositories.CPlatform}
+				err = config.Update(cfg.GitHub.Token.Value, opts.Streams, cfg.Repositories.AllowDirty.Value, repoParams)
+				if err != nil {
+					return fmt.Errorf("failed to update config repos: %w", err)
+				}
 			}
 
-			if len(args) > 0 {
+			// arguments after -- are the command to run, not environments
+			if dash := cmd.ArgsLenAtDash(); dash >= 0 {
+				args = args[:dash]
+			}
+			opts.Command = unfilteredExecuteArgs()
+			opts.IsolatedKubeconfig = cfg.Kubernetes.IsolatedKubeconfig.Value
+
+			if len(args) == 1 && allFromTenant == "" {
 				availableEnvironments, err = environment.List(configpath.GetCorectlCPlatformDir("environments"))
 				if err == nil {
 					env, err := findEnvironmentByName(args[0], availableEnvironments)
@@ -89,12 +150,28 @@ func connectCmd(cfg *config.Config) *cobra.Command {
 					}
 					opts.Environment = env
 				}
-				opts.Command = unfilteredExecuteArgs()
-			} else {
+				return connect(opts, cfg, bastion, availableEnvironments)
+			}
+			if len(args) == 0 && allFromTenant == "" {
 				return fmt.Errorf("please specify the environment as the 1st argument, one of: {%s}", strings.Join(envNames, "|"))
 			}
 
-			return connect(opts, cfg, availableEnvironments)
+			availableEnvironments, err = environment.List(configpath.GetCorectlCPlatformDir("environments"))
+			if err != nil {
+				return fmt.Errorf("unable to load environments: %w", err)
+			}
+			if allFromTenant != "" {
+				tenantEnvs, err := tenantEnvironments(allFromTenant)
+				if err != nil {
+					return err
+				}
+				args = append(args, tenantEnvs...)
+			}
+			environments, err := findEnvironmentsByName(args, availableEnvironments)
+			if err != nil {
+				return err
+			}
+			return connectAll(opts, cfg, bastion, environments)
 		},
 	}
 
@@ -113,6 +190,66 @@ func connectCmd(cfg *config.Config) *cobra.Command {
 		"Run in background",
 	)
 
+	connectCmd.Flags().BoolVar(
+		&opts.Socks5,
+		"socks5",
+		false,
+		"Serve a SOCKS5 proxy on the local port, for use with ALL_PROXY=socks5://localhost:<port>",
+	)
+
+	connectCmd.Flags().IntVar(
+		&opts.MetricsPort,
+		"metrics-port",
+		0,
+		"Local port to serve the metrics of the proxies on, in the Prometheus format at /metrics",
+	)
+
+	connectCmd.Flags().StringVar(
+		&bastion.Instance,
+		"bastion-instance",
+		"",
+		"Name of the bastion instance, defaults to <environment>-bastion",
+	)
+	connectCmd.Flags().StringVar(
+		&bastion.Zone,
+		"bastion-zone",
+		"",
+		"Zone of the bastion instance, defaults to the first zone of the environment region",
+	)
+	connectCmd.Flags().StringVar(
+		&bastion.Interface,
+		"bastion-interface",
+		"",
+		fmt.Sprintf("Network interface of the bastion instance, defaults to %s", corectlenv.DefaultInterfaceName),
+	)
+	connectCmd.Flags().IntVar(
+		&bastion.Port,
+		"bastion-port",
+		0,
+		fmt.Sprintf("Port of the squid proxy on the bastion instance, defaults to %d", corectlenv.BastionSquidProxyPort),
+	)
+
+	connectCmd.Flags().StringVar(
+		&allFromTenant,
+		"all-from-tenant",
+		"",
+		"Connect to all the environments of the given tenant",
+	)
+
+	connectCmd.Flags().BoolVar(
+		&opts.ProdConfirmed,
+		corectlenv.ProdConfirmationFlag,
+		false,
+		"Connect to production environments without asking for confirmation",
+	)
+
+	connectCmd.Flags().BoolVar(
+		&opts.SkipTunnel,
+		"skip-tunnel",
+		false,
+		"Only write the kubeconfig entry of the environment, without a proxy, for clusters reachable without the tunnel",
+	)
+
 	connectCmd.Flags().BoolVarP(
 		&opts.Force,
 		"force",
@@ -121,6 +258,10 @@ func connectCmd(cfg *config.Config) *cobra.Command {
 		"Force replacement of existing connection",
 	)
 
+	config.RegisterBoolParameterAsFlag(
+		&cfg.Kubernetes.IsolatedKubeconfig,
+		connectCmd.Flags(),
+	)
 	config.RegisterBoolParameterAsFlag(
 		&cfg.Repositories.AllowDirty,
 		connectCmd.Flags(),
@@ -128,7 +269,7 @@ func connectCmd(cfg *config.Config) *cobra.Command {
 	return connectCmd
 }
 
-func connect(opts corectlenv.EnvConnectOpts, cfg *config.Config, availableEnvironments []environment.Environment) error {
+func connect(opts corectlenv.EnvConnectOpts, cfg *config.Config, bastion corectlenv.Bastion, availableEnvironments []environment.Environment) error {
 	inputEnv := createEnvInputSwitch(opts, availableEnvironments)
 	envOutput, err := inputEnv.GetValue(opts.Streams)
 	if err != nil {
@@ -139,22 +280,31 @@ func connect(opts corectlenv.EnvConnectOpts, cfg *config.Config, availableEnviro
 		return err
 	}
 	opts.Environment = env
+	opts.BastionOverrides = bastionOverrides(cfg, bastion, env)
+	opts.PinnedPorts = pinnedPorts(cfg)
 
 	ctx := context.Background()
-	gcpClient, err := setupSvc(ctx)
-
-	if err != nil {
-		return err
+	switch p := env.Platform.(type) {
+	case *environment.GCPVendor:
+		gcpClient, err := setupSvc(ctx)
+		if err != nil {
+			return err
+		}
+		opts.GcpClient = gcpClient
+		opts.ProjectID = p.ProjectId
+		opts.Region = p.Region
+	case *environment.AWSVendor:
+		opts.ProjectID = p.AccountId
+		opts.Region = p.Region
 	}
-	opts.GcpClient = gcpClient
 
-	if err := corectlenv.Validate(ctx, env, opts.Exec, opts.GcpClient); err != nil {
-		return err
+	// The environment was validated by the parent of the background child
+	if !corectlenv.IsConnectChild(opts) {
+		if err := corectlenv.Validate(ctx, env, opts.Bastion(env), opts.Exec, opts.GcpClient); err != nil {
+			return err
+		}
 	}
 
-	gcpEnv := env.Platform.(*environment.GCPVendor)
-	opts.ProjectID = gcpEnv.ProjectId
-	opts.Region = gcpEnv.Region
 	if err := corectlenv.Connect(opts); err != nil {
 		return err
 	}
@@ -162,12 +312,123 @@ func connect(opts corectlenv.EnvConnectOpts, cfg *config.Config, availableEnviro
 	return nil
 }
 
+func connectAll(opts corectlenv.EnvConnectOpts, cfg *config.Config, bastion corectlenv.Bastion, environments []*environment.Environment) error {
+	if opts.Port != 0 {
+		return errors.New("--port can only be used with a single environment")
+	}
+	// the bastion of each environment is different, per environment values go in the config
+	if flag := bastionFlag(bastion); flag != "" {
+		return fmt.Errorf("--%s can only be used with a single environment, set environments.<name>.bastion in the config instead", flag)
+	}
+	opts.BastionOverrides = bastionOverrides(cfg, bastion, environments...)
+	opts.PinnedPorts = pinnedPorts(cfg)
+
+	ctx := context.Background()
+	for _, env := range environments {
+		if _, ok := env.Platform.(*environment.GCPVendor); ok && opts.GcpClient == nil {
+			gcpClient, err := setupSvc(ctx)
+			if err != nil {
+				return err
+			}
+			opts.GcpClient = gcpClient
+		}
+		// the environments were validated by the parent of the background child
+		if corectlenv.IsConnectChild(opts) {
+			continue
+		}
+		if err := corectlenv.Validate(ctx, env, opts.Bastion(env), opts.Exec, opts.GcpClient); err != nil {
+			return fmt.Errorf("[%s] %w", env.Environment, err)
+		}
+	}
+
+	return corectlenv.ConnectAll(opts, environments)
+}
+
+// bastionFlag returns the name of the first --bastion-* flag set, empty when none is.
+func bastionFlag(bastion corectlenv.Bastion) string {
+	switch {
+	case bastion.Instance != "":
+		return "bastion-instance"
+	case bastion.Zone != "":
+		return "bastion-zone"
+	case bastion.Interface != "":
+		return "bastion-interface"
+	case bastion.Port != 0:
+		return "bastion-port"
+	}
+	return ""
+}
+
+// bastionOverrides returns the bastion overrides for the environments from the config, with the flags taking precedence.
+func bastionOverrides(cfg *config.Config, flags corectlenv.Bastion, environments ...*environment.Environment) map[string]corectlenv.Bastion {
+	overrides := make(map[string]corectlenv.Bastion, len(environments))
+	for _, env := range environments {
+		c := cfg.Environments[env.Environment].Bastion
+		overrides[env.Environment] = corectlenv.Bastion{
+			Instance:  c.Instance,
+			Zone:      c.Zone,
+			Interface: c.Interface,
+			Port:      c.Port,
+		}.With(flags)
+	}
+	return overrides
+}
+
+// pinnedPorts returns the ports of the proxies pinned in the config, by environment name.
+func pinnedPorts(cfg *config.Config) map[string]int {
+	ports := make(map[string]int)
+	for name, c := range cfg.Environments {
+		if c.ProxyPort != 0 {
+			ports[name] = c.ProxyPort
+		}
+	}
+	return ports
+}
+
+// findEnvironmentsByName resolves the named environments, ignoring duplicates.
+func findEnvironmentsByName(names []string, environments []environment.Environment) ([]*environment.Environment, error) {
+	var found []*environment.Environment
+	for _, name := range names {
+		if slices.ContainsFunc(found, func(env *environment.Environment) bool { return env.Environment == name }) {
+			continue
+		}
+		env, err := findEnvironmentByName(name, environments)
+		if err != nil {
+			return nil, err
+		}
+		found = append(found, env)
+	}
+	return found, nil
+}
+
+func tenantEnvironments(name string) ([]string, error) {
+	t, err := tenant.FindByName(configpath.GetCorectlCPlatformDir("tenants"), name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find the tenant: %w", err)
+	}
+	if t == nil {
+		return nil, fmt.Errorf("tenant is not found: %s", name)
+	}
+	if len(t.Environments) == 0 {
+		return nil, fmt.Errorf("tenant %s has no environments", name)
+	}
+	return t.Environments, nil
+}
+
 func setupSvc(ctx context.Context) (*gcp.Client, error) {
 	clusterClient, err := gcp.NewClusterClient(ctx)
 	if err != nil {
 		return nil, err
 	}
-	gcpClient, err := gcp.NewClient(clusterClient)
+	computeSvc, err := gcp.NewComputeService(ctx)
+	if err != nil {
+		return nil, err
+	}
+	resourceManagerSvc, err := gcp.NewResourceManagerService(ctx)
+	if err != nil {
+		return nil, err
+	}
+	gcpClient, err := gcp.NewClient(clusterClient, gcp.WithComputeService(computeSvc), gcp.WithResourceManagerService(resourceManagerSvc))
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,152 @@
+//go:build !windows
+
+package env
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/coreeng/core-platform/pkg/environment"
+	"github.com/coreeng/corectl/pkg/cmdutil/config"
+	"github.com/coreeng/corectl/pkg/cmdutil/userio"
+	corectlenv "github.com/coreeng/corectl/pkg/env"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// The test binary stands in for the aws cli and for the command run through the proxy, depending on the name it is run as.
+func TestMain(m *testing.M) {
+	switch filepath.Base(os.Args[0]) {
+	case "aws":
+		os.Exit(fakeAws(os.Args[1:]))
+	case "proxy-client":
+		os.Exit(proxyClient(os.Args[1]))
+	}
+	os.Exit(m.Run())
+}
+
+// fakeAws answers the aws cli calls made to connect to an eks cluster, the ssm session echoing what it receives.
+func fakeAws(args []string) int {
+	switch {
+	case len(args) > 0 && args[0] == "help":
+		return 0
+	case len(args) > 1 && args[0] == "eks" && args[1] == "describe-cluster":
+		fmt.Printf("https://cluster.example.com\t%s\n", base64.StdEncoding.EncodeToString([]byte("ca")))
+		return 0
+	case len(args) > 1 && args[0] == "ec2" && args[1] == "describe-instances":
+		fmt.Println("i-0123456789")
+		return 0
+	case len(args) > 1 && args[0] == "ssm" && args[1] == "start-session":
+		var localPort string
+		for _, param := range strings.Split(args[len(args)-1], ",") {
+			if port, ok := strings.CutPrefix(param, "localPortNumber="); ok {
+				localPort = port
+			}
+		}
+		listener, err := net.Listen("tcp", "localhost:"+localPort)
+		if err != nil {
+			return 1
+		}
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return 1
+			}
+			go func() {
+				defer func() { _ = conn.Close() }()
+				_, _ = io.Copy(conn, conn)
+			}()
+		}
+	}
+	return 1
+}
+
+// proxyClient sends a message through the proxy at the address, succeeding when it comes back from the tunnel.
+func proxyClient(address string) int {
+	conn, err := net.Dial("tcp", address)
+	if err != nil {
+		return 1
+	}
+	defer func() { _ = conn.Close() }()
+	if _, err := conn.Write([]byte("ping")); err != nil {
+		return 1
+	}
+	reply := make([]byte, 4)
+	if _, err := io.ReadFull(conn, reply); err != nil || string(reply) != "ping" {
+		return 1
+	}
+	return 0
+}
+
+func TestConnectStartsTheProxy(t *testing.T) {
+	bin := t.TempDir()
+	executable, err := os.Executable()
+	require.NoError(t, err)
+	for _, name := range []string{"aws", "proxy-client"} {
+		require.NoError(t, os.Symlink(executable, filepath.Join(bin, name)))
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Setenv("CORECTL_HOME", t.TempDir())
+	kubeconfig := filepath.Join(t.TempDir(), "config")
+	t.Setenv("KUBECONFIG", kubeconfig)
+
+	cfg := config.NewConfig()
+	cmd := connectCmd(cfg)
+	require.NoError(t, cmd.ParseFlags(nil))
+	assert.Equal(t, "false", cmd.Flags().Lookup("skip-tunnel").Value.String())
+
+	env := environment.Environment{
+		Environment: fmt.Sprintf("connect-test-%d", os.Getpid()),
+		Tier:        environment.DevEnvironmentTier,
+		Platform: &environment.AWSVendor{
+			AccountId: "123456789012",
+			Region:    "eu-west-2",
+		},
+	}
+	port := freePort(t)
+	opts := newConnectOpts()
+	opts.Environment = &env
+	opts.Port = port
+	opts.Command = []string{"proxy-client", fmt.Sprintf("localhost:%d", port)}
+	opts.Streams = userio.NewIOStreamsWithInteractive(os.Stdin, os.Stdout, os.Stderr, false)
+
+	err = connect(opts, cfg, corectlenv.Bastion{}, []environment.Environment{env})
+	require.NoError(t, err, "the command run through the proxy should reach the tunnel")
+
+	kubeConfig, err := clientcmd.LoadFromFile(kubeconfig)
+	require.NoError(t, err)
+	cluster := fmt.Sprintf("arn:aws:eks:eu-west-2:123456789012:cluster/%s", env.Environment)
+	assert.Equal(t, fmt.Sprintf("http://localhost:%d", port), kubeConfig.Clusters[cluster].ProxyURL)
+}
+
+func TestConnectAllRejectsBastionFlags(t *testing.T) {
+	environments := []*environment.Environment{
+		{Environment: "eu-env", Platform: &environment.GCPVendor{ProjectId: "eu-project", Region: "europe-west2"}},
+		{Environment: "us-env", Platform: &environment.GCPVendor{ProjectId: "us-project", Region: "us-central1"}},
+	}
+	for flag, bastion := range map[string]corectlenv.Bastion{
+		"bastion-instance":  {Instance: "bastion"},
+		"bastion-zone":      {Zone: "us-central1-a"},
+		"bastion-interface": {Interface: "nic1"},
+		"bastion-port":      {Port: 3129},
+	} {
+		t.Run(flag, func(t *testing.T) {
+			err := connectAll(newConnectOpts(), config.NewConfig(), bastion, environments)
+			assert.ErrorContains(t, err, "--"+flag+" can only be used with a single environment")
+		})
+	}
+}
+
+func freePort(t *testing.T) int {
+	l, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	defer func() { _ = l.Close() }()
+	return l.Addr().(*net.TCPAddr).Port
+}
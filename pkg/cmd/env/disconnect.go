@@ -27,11 +27,18 @@ func disconnectCmd(cfg *config.Config) *cobra.Command {
 			command.WithStderr(os.Stderr),
 		),
 	}
+	var all bool
 	disconnectCmd := &cobra.Command{
-		Use:   "disconnect <environment>",
+		Use:   "disconnect <environment>...",
 		Short: "Disconnect from an environment",
-		Long:  `This command allows you to disconnect from a specified environment.`,
-		Args:  cobra.MinimumNArgs(1),
+		Long: `This command allows you to disconnect from a specified environment, or from all of them with --all.
+The proxy and the forwards of the environments are stopped, waiting for the active connections of the proxy to drain.`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if all {
+				return cobra.NoArgs(cmd, args)
+			}
+			return cobra.MinimumNArgs(1)(cmd, args)
+		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			var (
 				err                   error
@@ -61,6 +68,9 @@ func disconnectCmd(cfg *config.Config) *cobra.Command {
 			if err != nil {
 				return fmt.Errorf("unable to load environments")
 			}
+			if all {
+				return disconnect(opts, cfg, availableEnvironments)
+			}
 			var selectedEnvironments []environment.Environment
 			// iterate over args adding the environment when the find it
 			for _, arg := range args {
@@ -77,6 +87,13 @@ func disconnectCmd(cfg *config.Config) *cobra.Command {
 		},
 	}
 
+	disconnectCmd.Flags().BoolVar(
+		&all,
+		"all",
+		false,
+		"Disconnect from all the environments with a running proxy",
+	)
+
 	config.RegisterBoolParameterAsFlag(
 		&cfg.Repositories.AllowDirty,
 		disconnectCmd.Flags(),
@@ -85,15 +102,17 @@ func disconnectCmd(cfg *config.Config) *cobra.Command {
 }
 
 func disconnect(opts corectlenv.EnvConnectOpts, cfg *config.Config, environments []environment.Environment) error {
-	// Call getProxyPids to get pids
-	pids, err := corectlenv.GetProxyPids(environments)
-	_ = err
-	for name, pid := range pids {
-		// Kill the process with the pid
-		if err := corectlenv.KillProcess(name, pid.Pid, false); err != nil {
-			return fmt.Errorf("[%s] failed to kill process: %w", name, err)
+	proxies := corectlenv.GetActiveProxies(environments)
+	for name, proxy := range proxies {
+		if err := corectlenv.ShutdownProxy(name); err != nil {
+			return fmt.Errorf("[%s] %w", name, err)
+		}
+		logger.Warn().Msgf("Proxy for %s with pid %d stopped", name, proxy.Pid)
+	}
+	for _, env := range environments {
+		if err := corectlenv.ShutdownForwards(env.Environment); err != nil {
+			return err
 		}
-		logger.Warn().Msgf("Proxy for %s with pid %d stopped", name, pid.Pid)
 	}
 	return nil
 }
@@ -20,6 +20,10 @@ func NewEnvCmd(cfg *config.Config) *cobra.Command {
 	envCmd.AddCommand(openResource(cfg))
 	envCmd.AddCommand(disconnectCmd(cfg))
 	envCmd.AddCommand(activeCmd(cfg))
+	envCmd.AddCommand(statusCmd(cfg))
+	envCmd.AddCommand(validateCmd(cfg))
+	envCmd.AddCommand(forwardCmd(cfg))
+	envCmd.AddCommand(kubeCredentialsCmd())
 
 	return envCmd
 }
@@ -0,0 +1,106 @@
+package env
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/coreeng/core-platform/pkg/environment"
+	"github.com/coreeng/corectl/pkg/cmdutil/config"
+	"github.com/coreeng/corectl/pkg/cmdutil/configpath"
+	"github.com/coreeng/corectl/pkg/cmdutil/userio"
+	"github.com/coreeng/corectl/pkg/command"
+	corectlenv "github.com/coreeng/corectl/pkg/env"
+	"github.com/spf13/cobra"
+)
+
+type ForwardOpts struct {
+	Streams   userio.IOStreams
+	LocalPort int
+}
+
+func forwardCmd(cfg *config.Config) *cobra.Command {
+	var opts = ForwardOpts{}
+	forwardCmd := &cobra.Command{
+		Use:   "forward <environment> <namespace>/<service>:<port>",
+		Short: "Forward a local port to a service in an environment",
+		Long: `This command allows you to forward a local port to a service running in the cluster of an environment.
+
+The forward goes through the proxy of the environment, which has to be started with env connect first,
+and is restarted whenever it fails until the command is interrupted.`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+
+			nonInteractive, err := cmd.Flags().GetBool("non-interactive")
+			if err != nil {
+				nonInteractive = true
+			}
+
+			opts.Streams = userio.NewIOStreamsWithInteractive(
+				cmd.InOrStdin(),
+				cmd.OutOrStdout(),
+				cmd.OutOrStderr(),
+				!nonInteractive,
+			)
+
+			target, err := corectlenv.ParseForwardTarget(args[1])
+			if err != nil {
+				return err
+			}
+
+			repoParams := []config.Parameter[string]{cfg.Repositories.CPlatform}
+			err = config.Update(cfg.GitHub.Token.Value, opts.Streams, cfg.Repositories.AllowDirty.Value, repoParams)
+			if err != nil {
+				return fmt.Errorf("failed to update config repos: %w", err)
+			}
+
+			availableEnvironments, err := environment.List(configpath.GetCorectlCPlatformDir("environments"))
+			if err != nil {
+				return fmt.Errorf("unable to load environments")
+			}
+			env, err := findEnvironmentByName(args[0], availableEnvironments)
+			if err != nil {
+				return err
+			}
+
+			return forward(opts, cfg, env, target)
+		},
+	}
+
+	forwardCmd.Flags().IntVar(
+		&opts.LocalPort,
+		"local-port",
+		0,
+		"Local port to forward, defaults to the port of the service",
+	)
+
+	config.RegisterBoolParameterAsFlag(
+		&cfg.Kubernetes.IsolatedKubeconfig,
+		forwardCmd.Flags(),
+	)
+	config.RegisterBoolParameterAsFlag(
+		&cfg.Repositories.AllowDirty,
+		forwardCmd.Flags(),
+	)
+	return forwardCmd
+}
+
+func forward(opts ForwardOpts, cfg *config.Config, env *environment.Environment, target corectlenv.ForwardTarget) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	connectOpts := corectlenv.EnvConnectOpts{
+		Environment:        env,
+		Streams:            opts.Streams,
+		IsolatedKubeconfig: cfg.Kubernetes.IsolatedKubeconfig.Value,
+		SilentExec: command.NewCommander(
+			command.WithStdout(&bytes.Buffer{}),
+			command.WithStderr(&bytes.Buffer{}),
+		),
+	}
+	return corectlenv.Forward(ctx, connectOpts, target, opts.LocalPort)
+}
@@ -0,0 +1,25 @@
+package env
+
+import (
+	corectlenv "github.com/coreeng/corectl/pkg/env"
+	"github.com/spf13/cobra"
+)
+
+// kubeCredentialsCmd is the exec plugin referenced by the kubeconfig entries written by env connect for gcp clusters,
+// so kubectl authenticates with the default google credentials without needing gcloud.
+func kubeCredentialsCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:    "kube-credentials",
+		Short:  "Print credentials for kubectl",
+		Hidden: true,
+		Args:   cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			credential, err := corectlenv.ExecCredential(cmd.Context())
+			if err != nil {
+				return err
+			}
+			_, err = cmd.OutOrStdout().Write(append(credential, '\n'))
+			return err
+		},
+	}
+}
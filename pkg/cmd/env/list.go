@@ -5,6 +5,7 @@ import (
 	"github.com/coreeng/core-platform/pkg/environment"
 	"github.com/coreeng/corectl/pkg/cmdutil/config"
 	"github.com/coreeng/corectl/pkg/cmdutil/configpath"
+	"github.com/coreeng/corectl/pkg/cmdutil/output"
 	"github.com/coreeng/corectl/pkg/cmdutil/userio"
 	corectlenv "github.com/coreeng/corectl/pkg/env"
 	"github.com/coreeng/corectl/pkg/logger"
@@ -13,6 +14,7 @@ import (
 
 type ListOpt struct {
 	RepositoryLocation string
+	Output             string
 	Streams            userio.IOStreams
 }
 
@@ -21,8 +23,15 @@ func listCmd(cfg *config.Config) *cobra.Command {
 	listCmd := &cobra.Command{
 		Use:   "list",
 		Short: "List all environments",
+		Long: `This command lists all the environments, along with the local port assigned to their proxy, if any.
+
+With -o json or -o yaml every environment is printed with the fields documented in corectl env active --help,
+along with its running proxy, if any.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			logger.Info().Msgf("Invoked with args: %+v", opts)
+			if err := output.Validate(opts.Output, output.Table, output.JSON, output.YAML); err != nil {
+				return err
+			}
 			opts.Streams = userio.NewIOStreams(
 				cmd.InOrStdin(),
 				cmd.OutOrStdout(),
@@ -33,6 +42,7 @@ func listCmd(cfg *config.Config) *cobra.Command {
 		},
 	}
 
+	output.RegisterFlag(listCmd, &opts.Output, output.Table, output.JSON, output.YAML)
 	config.RegisterBoolParameterAsFlag(
 		&cfg.Repositories.AllowDirty,
 		listCmd.Flags(),
@@ -53,7 +63,27 @@ func list(opts ListOpt, cfg *config.Config) error {
 		return fmt.Errorf("could not find repository location: %w", err)
 	}
 
-	table := corectlenv.NewTable(opts.Streams, false)
+	registry, err := corectlenv.LoadPortRegistry(pinnedPorts(cfg))
+	if err != nil {
+		return err
+	}
+	ports := registry.Ports()
+
+	if opts.Output != output.Table {
+		proxies := corectlenv.GetActiveProxies(existing)
+		envs := make([]corectlenv.EnvironmentOutput, len(existing))
+		for i, env := range existing {
+			var info *corectlenv.ProxyInfo
+			if proxy, exists := proxies[env.Environment]; exists {
+				info = &proxy
+			}
+			envs[i] = corectlenv.NewEnvironmentOutput(env, info, nil)
+			envs[i].Port = ports[env.Environment]
+		}
+		return output.Encode(opts.Streams.GetOutput(), opts.Output, envs)
+	}
+
+	table := corectlenv.NewTableWithPorts(opts.Streams, ports)
 	for _, env := range existing {
 		table.AppendEnv(env, "-", "-")
 	}
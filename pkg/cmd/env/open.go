@@ -1,12 +1,18 @@
 package env
 
 import (
+	"context"
 	"fmt"
-	"github.com/coreeng/corectl/pkg/cmdutil/configpath"
+	"net"
+	"net/url"
+	"os"
+	"os/signal"
 	"strings"
+	"syscall"
 
 	"github.com/coreeng/core-platform/pkg/environment"
 	"github.com/coreeng/corectl/pkg/cmdutil/config"
+	"github.com/coreeng/corectl/pkg/cmdutil/configpath"
 	"github.com/coreeng/corectl/pkg/cmdutil/userio"
 	corectlenv "github.com/coreeng/corectl/pkg/env"
 	"github.com/coreeng/corectl/pkg/logger"
@@ -17,6 +23,9 @@ import (
 type EnvOpenResourceOpt struct {
 	Environment string
 	Resource    string
+	Tenant      string
+	List        bool
+	Print       string
 
 	Streams userio.IOStreams
 }
@@ -24,12 +33,39 @@ type EnvOpenResourceOpt struct {
 func openResource(cfg *config.Config) *cobra.Command {
 	var opts EnvOpenResourceOpt
 	cmd := cobra.Command{
-		Use:   "open <environment> <resource>",
-		Short: "Open a resource of environment. Available resources are: " + strings.Join(corectlenv.ResourceStringList(), ", "),
-		Args:  cobra.ExactArgs(2),
+		Use:   "open <environment> [resource]",
+		Short: "Open a resource of environment",
+		Long: `This command opens a resource of the environment in the browser.
+
+The built-in resources are: ` + strings.Join(corectlenv.ResourceNames(corectlenv.DefaultResources()), ", ") + `.
+More resources can be defined in ` + corectlenv.ResourcesFile + ` of the environments repository, or under resources
+in the corectl config, with their url as a template over the fields of the environment, for example:
+
+  resources:
+    - name: argocd
+      description: ArgoCD of the environment
+      url: https://argocd.{{ .InternalServices.Domain }}
+
+Resources scoped to a tenant (tenant: true) use {{ tenant }} in their url and need --tenant.
+Use --list to show the resources available for the environment.
+
+When a proxy to the environment is running (see env connect), the resource is opened through it:
+a local proxy forwarding to the resource through the tunnel runs until interrupted, or until it has been
+idle for ` + corectlenv.ResourceProxyIdleTimeout.String() + `, and the browser is opened on its local address.
+Use --print url to print the url of the resource instead of opening it, or --print pac to print a proxy
+auto-config file sending the internal services of the environment through the running proxy.`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if opts.List {
+				return cobra.ExactArgs(1)(cmd, args)
+			}
+			return cobra.ExactArgs(2)(cmd, args)
+		},
+		ValidArgsFunction: completeOpenArgs(cfg),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			opts.Environment = args[0]
-			opts.Resource = args[1]
+			if len(args) > 1 {
+				opts.Resource = args[1]
+			}
 			opts.Streams = userio.NewIOStreams(
 				cmd.InOrStdin(),
 				cmd.OutOrStdout(),
@@ -38,6 +74,25 @@ func openResource(cfg *config.Config) *cobra.Command {
 			return run(cfg, &opts)
 		},
 	}
+	cmd.Flags().BoolVar(
+		&opts.List,
+		"list",
+		false,
+		"List the resources available for the environment",
+	)
+	cmd.Flags().StringVar(
+		&opts.Tenant,
+		"tenant",
+		"",
+		"Tenant to scope the resource to",
+	)
+	cmd.Flags().StringVar(
+		&opts.Print,
+		"print",
+		"",
+		"Print instead of opening the resource: url, or pac for a proxy auto-config file",
+	)
+	_ = cmd.RegisterFlagCompletionFunc("print", cobra.FixedCompletions([]string{printURL, printPAC}, cobra.ShellCompDirectiveNoFileComp))
 	config.RegisterBoolParameterAsFlag(
 		&cfg.Repositories.AllowDirty,
 		cmd.Flags(),
@@ -45,7 +100,15 @@ func openResource(cfg *config.Config) *cobra.Command {
 	return &cmd
 }
 
+const (
+	printURL = "url"
+	printPAC = "pac"
+)
+
 func run(cfg *config.Config, opts *EnvOpenResourceOpt) error {
+	if opts.Print != "" && opts.Print != printURL && opts.Print != printPAC {
+		return fmt.Errorf("invalid --print %q, must be %s or %s", opts.Print, printURL, printPAC)
+	}
 	repoParams := []config.Parameter[string]{cfg.Repositories.CPlatform}
 	err := config.Update(cfg.GitHub.Token.Value, opts.Streams, cfg.Repositories.AllowDirty.Value, repoParams)
 	if err != nil {
@@ -60,12 +123,119 @@ func run(cfg *config.Config, opts *EnvOpenResourceOpt) error {
 		return fmt.Errorf("environment %s not found", opts.Environment)
 	}
 
-	resourceType := corectlenv.ResourceType(opts.Resource)
-	if url, err := corectlenv.OpenResource(resourceType, env); err != nil {
+	resources, err := resourceCatalogue(cfg)
+	if err != nil {
+		return err
+	}
+	if opts.List {
+		table := corectlenv.NewResourceTable(opts.Streams)
+		for _, r := range resources {
+			table.AppendResource(r, env, opts.Tenant)
+		}
+		table.Render()
+		return nil
+	}
+
+	proxy, proxyErr := corectlenv.ActiveProxyURL(env.Environment)
+	if opts.Print == printPAC {
+		if proxyErr != nil {
+			return fmt.Errorf("no proxy running for %s, start one with env connect: %w", env.Environment, proxyErr)
+		}
+		opts.Streams.Print(corectlenv.PACFile(env, proxy))
+		return nil
+	}
+
+	resource, err := corectlenv.FindResource(resources, opts.Resource)
+	if err != nil {
+		return fmt.Errorf("couldn't open %s: %w, available resources: %s", opts.Resource, err, strings.Join(corectlenv.ResourceNames(resources), ", "))
+	}
+	resourceUrl, err := resource.URLFor(env, opts.Tenant)
+	if err != nil {
 		return fmt.Errorf("couldn't open %s: %w", opts.Resource, err)
-	} else {
-		logger.Info().Msgf("Opening %s for env %s: %s", resourceType, env.Environment, url)
-		defer logger.Info().Msgf("Opened %s for env %s: %s", resourceType, env.Environment, url)
-		return browser.OpenURL(url)
+	}
+	if opts.Print == printURL {
+		opts.Streams.Print(resourceUrl)
+		if proxyErr == nil {
+			opts.Streams.Info(fmt.Sprintf("Reachable through the proxy %s, e.g. with --print %s", proxy, printPAC))
+		}
+		return nil
+	}
+	if proxyErr != nil {
+		logger.Info().Msgf("Opening %s for env %s: %s", resource.Name, env.Environment, resourceUrl)
+		defer logger.Info().Msgf("Opened %s for env %s: %s", resource.Name, env.Environment, resourceUrl)
+		return browser.OpenURL(resourceUrl)
+	}
+	return openThroughProxy(opts, resource, resourceUrl, proxy)
+}
+
+// openThroughProxy serves the resource locally, forwarding through the running proxy, and opens the browser on it.
+func openThroughProxy(opts *EnvOpenResourceOpt, resource corectlenv.Resource, resourceUrl string, proxy *url.URL) error {
+	target, err := url.Parse(resourceUrl)
+	if err != nil {
+		return fmt.Errorf("couldn't open %s: invalid url %s: %w", resource.Name, resourceUrl, err)
+	}
+	listener, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		return fmt.Errorf("couldn't open %s: %w", resource.Name, err)
+	}
+	local := *target
+	local.Scheme = "http"
+	local.Host = listener.Addr().String()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	upstream := &url.URL{Scheme: target.Scheme, Host: target.Host}
+	served := make(chan error, 1)
+	go func() {
+		served <- corectlenv.ServeResource(ctx, listener, corectlenv.NewResourceProxy(upstream, proxy), corectlenv.ResourceProxyIdleTimeout)
+	}()
+
+	opts.Streams.Info(fmt.Sprintf("Opening %s for env %s through the proxy %s at %s, press Ctrl+C to stop", resource.Name, opts.Environment, proxy, local.String()))
+	if err := browser.OpenURL(local.String()); err != nil {
+		stop()
+		<-served
+		return err
+	}
+	return <-served
+}
+
+// resourceCatalogue returns the built-in resources, along with the ones defined in the environments repository
+// and in the config, which replace the resources with the same name.
+func resourceCatalogue(cfg *config.Config) ([]corectlenv.Resource, error) {
+	repoResources, err := corectlenv.LoadResources(configpath.GetCorectlCPlatformDir(corectlenv.ResourcesFile))
+	if err != nil {
+		return nil, err
+	}
+	var cfgResources []corectlenv.Resource
+	for _, r := range cfg.Resources {
+		cfgResources = append(cfgResources, corectlenv.Resource{
+			Name:        r.Name,
+			Description: r.Description,
+			URL:         r.URL,
+			Tenant:      r.Tenant,
+		})
+	}
+	return corectlenv.MergeResources(corectlenv.DefaultResources(), repoResources, cfgResources), nil
+}
+
+// completeOpenArgs completes the environment names, then the resource names, from the local copy of the repositories.
+func completeOpenArgs(cfg *config.Config) cobra.CompletionFunc {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		switch len(args) {
+		case 0:
+			return completeEnvironmentNames(cmd, args, toComplete)
+		case 1:
+			resources, err := resourceCatalogue(cfg)
+			if err != nil {
+				return nil, cobra.ShellCompDirectiveError
+			}
+			var completions []string
+			for _, r := range resources {
+				completions = append(completions, r.Name+"\t"+r.Description)
+			}
+			return completions, cobra.ShellCompDirectiveNoFileComp
+		default:
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
 	}
 }
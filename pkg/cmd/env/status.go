@@ -0,0 +1,121 @@
+package env
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/coreeng/core-platform/pkg/environment"
+	"github.com/coreeng/corectl/pkg/cmdutil/config"
+	"github.com/coreeng/corectl/pkg/cmdutil/configpath"
+	"github.com/coreeng/corectl/pkg/cmdutil/output"
+	"github.com/coreeng/corectl/pkg/cmdutil/userio"
+	corectlenv "github.com/coreeng/corectl/pkg/env"
+	"github.com/coreeng/corectl/pkg/gcp"
+	"github.com/spf13/cobra"
+)
+
+type StatusOpt struct {
+	Output  string
+	Streams userio.IOStreams
+}
+
+func statusCmd(cfg *config.Config) *cobra.Command {
+	var opts = StatusOpt{}
+	statusCmd := &cobra.Command{
+		Use:   "status [environment...]",
+		Short: "Show the status of the clusters of environments",
+		Long: `This command shows the status of the cluster of each of the given environments, or of all the environments:
+its state, master version, release channel, maintenance window and node pools with their version and autoscaling.
+The clusters are queried concurrently. Use -o json for the output to be read by other tools.`,
+		ValidArgsFunction: completeEnvironmentNames,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+			if err := output.Validate(opts.Output, output.Table, output.JSON); err != nil {
+				return err
+			}
+			opts.Streams = userio.NewIOStreams(
+				cmd.InOrStdin(),
+				cmd.OutOrStdout(),
+				cmd.OutOrStderr(),
+			)
+
+			repoParams := []config.Parameter[string]{cfg.Repositories.CPlatform}
+			if err := config.Update(cfg.GitHub.Token.Value, opts.Streams, cfg.Repositories.AllowDirty.Value, repoParams); err != nil {
+				return fmt.Errorf("failed to update config repos: %w", err)
+			}
+			environments, err := environment.List(configpath.GetCorectlCPlatformDir("environments"))
+			if err != nil {
+				return fmt.Errorf("unable to load environments: %w", err)
+			}
+			if len(args) > 0 {
+				var selected []environment.Environment
+				for _, arg := range args {
+					env, err := findEnvironmentByName(arg, environments)
+					if err != nil {
+						return err
+					}
+					selected = append(selected, *env)
+				}
+				environments = selected
+			}
+
+			ctx := cmd.Context()
+			if ctx == nil {
+				ctx = context.Background()
+			}
+			var client *gcp.Client
+			if slices.ContainsFunc(environments, isGCP) {
+				clusterClient, err := gcp.NewClusterClient(ctx)
+				if err != nil {
+					return err
+				}
+				if client, err = gcp.NewClient(clusterClient); err != nil {
+					return err
+				}
+			}
+			return status(ctx, opts, client, environments)
+		},
+	}
+	output.RegisterFlag(statusCmd, &opts.Output, output.Table, output.JSON)
+
+	config.RegisterBoolParameterAsFlag(
+		&cfg.Repositories.AllowDirty,
+		statusCmd.Flags(),
+	)
+	return statusCmd
+}
+
+func status(ctx context.Context, opts StatusOpt, client *gcp.Client, environments []environment.Environment) error {
+	statuses := corectlenv.GetClusterStatuses(ctx, client, environments)
+	if opts.Output != output.Table {
+		return output.Encode(opts.Streams.GetOutput(), opts.Output, statuses)
+	}
+
+	table := corectlenv.NewClusterStatusTable(opts.Streams)
+	for _, s := range statuses {
+		table.AppendStatus(s)
+	}
+	table.Render()
+	return nil
+}
+
+func isGCP(env environment.Environment) bool {
+	_, ok := env.Platform.(*environment.GCPVendor)
+	return ok
+}
+
+// completeEnvironmentNames completes the names of the environments from the local copy of the repositories.
+func completeEnvironmentNames(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	environments, err := environment.List(configpath.GetCorectlCPlatformDir("environments"))
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+	var names []string
+	for _, env := range environments {
+		if !slices.Contains(args, env.Environment) {
+			names = append(names, env.Environment)
+		}
+	}
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
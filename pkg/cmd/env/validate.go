@@ -0,0 +1,89 @@
+package env
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/coreeng/core-platform/pkg/environment"
+	"github.com/coreeng/corectl/pkg/cmdutil/config"
+	"github.com/coreeng/corectl/pkg/cmdutil/configpath"
+	"github.com/coreeng/corectl/pkg/cmdutil/userio"
+	"github.com/coreeng/corectl/pkg/command"
+	corectlenv "github.com/coreeng/corectl/pkg/env"
+	"github.com/coreeng/corectl/pkg/gcp"
+	"github.com/spf13/cobra"
+)
+
+type ValidateOpt struct {
+	Environment string
+	Streams     userio.IOStreams
+}
+
+func validateCmd(cfg *config.Config) *cobra.Command {
+	var opts = ValidateOpt{}
+	validateCmd := &cobra.Command{
+		Use:   "validate <environment>",
+		Short: "Check that an environment can be connected to",
+		Long: `This command checks that you can connect to the environment, as env connect does before connecting:
+that you have the IAM permissions needed on the project of GCP environments, and that its cluster and bastion exist.
+
+Every permission is listed with whether you have it and a predefined role granting it, so missing permissions
+can be requested precisely.`,
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeEnvironmentNames,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+			opts.Environment = args[0]
+			opts.Streams = userio.NewIOStreams(
+				cmd.InOrStdin(),
+				cmd.OutOrStdout(),
+				cmd.OutOrStderr(),
+			)
+			return validate(opts, cfg)
+		},
+	}
+	config.RegisterBoolParameterAsFlag(
+		&cfg.Repositories.AllowDirty,
+		validateCmd.Flags(),
+	)
+	return validateCmd
+}
+
+func validate(opts ValidateOpt, cfg *config.Config) error {
+	repoParams := []config.Parameter[string]{cfg.Repositories.CPlatform}
+	if err := config.Update(cfg.GitHub.Token.Value, opts.Streams, cfg.Repositories.AllowDirty.Value, repoParams); err != nil {
+		return fmt.Errorf("failed to update config repos: %w", err)
+	}
+	environments, err := environment.List(configpath.GetCorectlCPlatformDir("environments"))
+	if err != nil {
+		return fmt.Errorf("unable to load environments: %w", err)
+	}
+	env, err := findEnvironmentByName(opts.Environment, environments)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	var gcpClient *gcp.Client
+	if isGCP(*env) {
+		if gcpClient, err = setupSvc(ctx); err != nil {
+			return err
+		}
+		checks, err := corectlenv.CheckPermissions(ctx, gcpClient, env)
+		if err != nil {
+			return err
+		}
+		table := corectlenv.NewPermissionTable(opts.Streams)
+		for _, check := range checks {
+			table.AppendCheck(check)
+		}
+		table.Render()
+	}
+
+	bastion := corectlenv.EnvConnectOpts{BastionOverrides: bastionOverrides(cfg, corectlenv.Bastion{}, env)}.Bastion(env)
+	if err := corectlenv.Validate(ctx, env, bastion, command.NewCommander(), gcpClient); err != nil {
+		return err
+	}
+	opts.Streams.Info(fmt.Sprintf("Environment %s is ready to connect to", env.Environment))
+	return nil
+}
@@ -1,8 +1,11 @@
 package cmd
 
 import (
+	"errors"
+
 	"github.com/coreeng/corectl/pkg/cmd/root"
 	"github.com/coreeng/corectl/pkg/cmdutil/config"
+	corectlenv "github.com/coreeng/corectl/pkg/env"
 	"github.com/coreeng/corectl/pkg/logger"
 )
 
@@ -17,6 +20,19 @@ func Run() int {
 
 	err = rootCmd.Execute()
 
+	// commands run through corectl exit with their own code, they already reported why
+	var commandErr corectlenv.CommandExitError
+	if errors.As(err, &commandErr) {
+		return commandErr.Code
+	}
+	// proxies stopped by a signal exit with its code, reporting only what went wrong while shutting down
+	var signalErr corectlenv.SignalExitError
+	if errors.As(err, &signalErr) {
+		if signalErr.Err != nil {
+			logger.Error().Msgf("Error: %v", signalErr.Err)
+		}
+		return signalErr.Code()
+	}
 	if err != nil {
 		logger.Error().Msgf("Error: %v", err)
 		return 1
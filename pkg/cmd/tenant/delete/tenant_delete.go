@@ -0,0 +1,178 @@
+package delete
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	coretnt "github.com/coreeng/core-platform/pkg/tenant"
+	"github.com/coreeng/corectl/pkg/cmdutil/config"
+	"github.com/coreeng/corectl/pkg/cmdutil/configpath"
+	"github.com/coreeng/corectl/pkg/cmdutil/userio"
+	"github.com/coreeng/corectl/pkg/cmdutil/userio/confirmation"
+	"github.com/coreeng/corectl/pkg/git"
+	"github.com/coreeng/corectl/pkg/logger"
+	"github.com/coreeng/corectl/pkg/tenant"
+	"github.com/google/go-github/v60/github"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+type TenantDeleteOpts struct {
+	TenantName     string
+	Cascade        bool
+	NonInteractive bool
+	DryRun         bool
+
+	Streams userio.IOStreams
+}
+
+func NewTenantDeleteCmd(cfg *config.Config) *cobra.Command {
+	opts := TenantDeleteOpts{}
+	tenantDeleteCmd := &cobra.Command{
+		Use:   "delete <tenant-name>",
+		Short: "Delete an org unit or a delivery unit",
+		Long: `This command deletes an org unit or a delivery unit through a PR to the platform repository.
+
+An org unit still owning delivery units is only deleted with --cascade, which deletes its delivery units too.
+The delivery units deleted and the repositories left without a tenant are listed before the PR is raised.
+With --dry-run, the files which would be removed are printed.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+			nonInteractive, err := cmd.Flags().GetBool("non-interactive")
+			if err != nil {
+				logger.Panic().With(zap.Error(err)).Msg("could not get non-interactive flag")
+			}
+			opts.NonInteractive = nonInteractive
+			opts.TenantName = args[0]
+			opts.Streams = userio.NewIOStreamsWithInteractive(
+				cmd.InOrStdin(),
+				cmd.OutOrStdout(),
+				cmd.OutOrStderr(),
+				!opts.NonInteractive,
+			)
+			return run(&opts, cfg)
+		},
+	}
+
+	tenantDeleteCmd.Flags().BoolVar(
+		&opts.Cascade,
+		"cascade",
+		false,
+		"Delete the delivery units of the org unit along with it",
+	)
+	tenantDeleteCmd.Flags().BoolVarP(
+		&opts.DryRun,
+		"dry-run",
+		"n",
+		false,
+		"Dry run",
+	)
+
+	config.RegisterStringParameterAsFlag(&cfg.GitHub.Token, tenantDeleteCmd.Flags())
+	config.RegisterBoolParameterAsFlag(&cfg.Repositories.AllowDirty, tenantDeleteCmd.Flags())
+
+	return tenantDeleteCmd
+}
+
+func run(opts *TenantDeleteOpts, cfg *config.Config) error {
+	repoParams := []config.Parameter[string]{cfg.Repositories.CPlatform}
+	err := config.Update(cfg.GitHub.Token.Value, opts.Streams, cfg.Repositories.AllowDirty.Value, repoParams)
+	if err != nil {
+		return fmt.Errorf("failed to update config repos: %w", err)
+	}
+
+	if opts.TenantName == coretnt.RootName {
+		return errors.New("cannot delete the root tenant")
+	}
+	existingTenants, err := coretnt.List(configpath.GetCorectlCPlatformDir("tenants"))
+	if err != nil {
+		return err
+	}
+	tenants, err := tenantsToDelete(existingTenants, opts.TenantName, opts.Cascade)
+	if err != nil {
+		return err
+	}
+	t := tenants[len(tenants)-1]
+
+	cplatformRepoPath := configpath.GetCorectlCPlatformDir()
+	files, err := tenant.DefinitionFiles(cplatformRepoPath, tenants)
+	if err != nil {
+		return err
+	}
+	if deliveryUnits := tenants[:len(tenants)-1]; len(deliveryUnits) > 0 {
+		opts.Streams.Warn(fmt.Sprintf("delivery units of %s to delete: %s", t.Name, strings.Join(names(deliveryUnits), ", ")))
+	}
+	if repos := tenant.Repos(tenants); len(repos) > 0 {
+		opts.Streams.Warn(fmt.Sprintf("repositories left without a tenant: %s", strings.Join(repos, ", ")))
+	}
+	if opts.DryRun {
+		opts.Streams.Info("files which would be removed:")
+		for _, file := range files {
+			opts.Streams.Print(file)
+		}
+	} else if opts.Streams.IsInteractive() {
+		confirmed, err := confirmation.GetInput(opts.Streams, fmt.Sprintf("Raise a PR to delete %s?", strings.Join(names(tenants), ", ")))
+		if err != nil {
+			return err
+		}
+		if !confirmed {
+			return errors.New("deletion cancelled")
+		}
+	}
+
+	githubClient := github.NewClient(nil).
+		WithAuthToken(cfg.GitHub.Token.Value)
+	gitAuth := git.UrlTokenAuthMethod(cfg.GitHub.Token.Value)
+
+	logger.Warn().Msgf("Deleting tenant %s in platform repository: %s", t.Name, cfg.Repositories.CPlatform.Value)
+	result, err := tenant.Delete(&tenant.DeleteOp{
+		Tenants:           tenants,
+		CplatformRepoPath: cplatformRepoPath,
+		BranchName:        fmt.Sprintf("delete-tenant-%s", t.Name),
+		CommitMessage:     fmt.Sprintf("Delete tenant %s", t.Name),
+		PRName:            fmt.Sprintf("Delete tenant %s", t.Name),
+		PRBody:            fmt.Sprintf("Deletes tenant '%s', removing:\n\n- %s", t.Name, strings.Join(files, "\n- ")),
+		GitAuth:           gitAuth,
+		DryRun:            opts.DryRun,
+	}, githubClient)
+	if err != nil {
+		return fmt.Errorf("failed to delete tenant: %w", err)
+	}
+
+	logger.Warn().Msgf("Created PR to delete tenant %s: %s", t.Name, result.PRUrl)
+	return nil
+}
+
+// tenantsToDelete returns the tenants deleted along with the named one, which comes last.
+// An org unit owning delivery units is only deleted with them when cascading.
+func tenantsToDelete(existingTenants []coretnt.Tenant, name string, cascade bool) ([]*coretnt.Tenant, error) {
+	var t *coretnt.Tenant
+	for i := range existingTenants {
+		if existingTenants[i].Name == name {
+			t = &existingTenants[i]
+		}
+	}
+	if t == nil {
+		return nil, fmt.Errorf("tenant is not found: %s", name)
+	}
+	if t.Kind != "OrgUnit" {
+		return []*coretnt.Tenant{t}, nil
+	}
+
+	deliveryUnits := tenant.DeliveryUnitsOf(existingTenants, t.Name)
+	if len(deliveryUnits) > 0 && !cascade {
+		return nil, fmt.Errorf("org unit %s still owns delivery units %s, delete them first or pass --cascade",
+			t.Name, strings.Join(names(deliveryUnits), ", "))
+	}
+	return append(deliveryUnits, t), nil
+}
+
+func names(tenants []*coretnt.Tenant) []string {
+	result := make([]string, len(tenants))
+	for i, t := range tenants {
+		result[i] = t.Name
+	}
+	return result
+}
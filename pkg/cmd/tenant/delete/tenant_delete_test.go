@@ -0,0 +1,49 @@
+package delete
+
+import (
+	"testing"
+
+	coretnt "github.com/coreeng/core-platform/pkg/tenant"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var existingTenants = []coretnt.Tenant{
+	{Name: "ou", Kind: "OrgUnit"},
+	{Name: "empty-ou", Kind: "OrgUnit"},
+	{Name: "du1", Kind: "DeliveryUnit", Owner: "ou"},
+	{Name: "du2", Kind: "DeliveryUnit", Owner: "ou"},
+}
+
+func TestTenantsToDeleteDeliveryUnit(t *testing.T) {
+	tenants, err := tenantsToDelete(existingTenants, "du1", false)
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"du1"}, names(tenants))
+}
+
+func TestTenantsToDeleteOrgUnitWithoutDeliveryUnits(t *testing.T) {
+	tenants, err := tenantsToDelete(existingTenants, "empty-ou", false)
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"empty-ou"}, names(tenants))
+}
+
+func TestTenantsToDeleteRefusesOrgUnitOwningDeliveryUnits(t *testing.T) {
+	_, err := tenantsToDelete(existingTenants, "ou", false)
+
+	assert.ErrorContains(t, err, "org unit ou still owns delivery units du1, du2")
+}
+
+func TestTenantsToDeleteCascadesToDeliveryUnits(t *testing.T) {
+	tenants, err := tenantsToDelete(existingTenants, "ou", true)
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"du1", "du2", "ou"}, names(tenants))
+}
+
+func TestTenantsToDeleteUnknownTenant(t *testing.T) {
+	_, err := tenantsToDelete(existingTenants, "unknown", true)
+
+	assert.ErrorContains(t, err, "tenant is not found: unknown")
+}
@@ -2,7 +2,10 @@ package list
 
 import (
 	"fmt"
+	"strings"
+
 	"github.com/coreeng/corectl/pkg/cmdutil/configpath"
+	"github.com/coreeng/corectl/pkg/cmdutil/output"
 
 	"github.com/coreeng/core-platform/pkg/tenant"
 	"github.com/coreeng/corectl/pkg/cmdutil/config"
@@ -12,16 +15,37 @@ import (
 )
 
 type TenantListOpts struct {
+	Output  string
+	Columns []string
+	Filter  corectltnt.Filter
+
 	Streams userio.IOStreams
 }
 
+var formats = []string{output.Table, output.JSON, output.YAML, output.CSV}
+
 func NewTenantListCmd(cfg *config.Config) *cobra.Command {
 	opts := TenantListOpts{}
 	tenantListCmd := &cobra.Command{
 		Use:   "list",
 		Short: "List tenants",
+		Long: `This command lists the tenants.
+
+The columns are chosen with --columns, among: ` + strings.Join(corectltnt.ColumnNames(), ", ") + `.
+A table lists ` + strings.Join(corectltnt.DefaultColumns, ", ") + ` by default, and the other formats all the columns.
+The structured formats use the names of the columns as field names, the same as in the tenant definitions,
+and csv has them as header.
+
+The tenants listed are filtered with --kind, --owner, --type, --environment the tenants have, --repo-missing
+for the delivery units without a repository, and --contact-email, a glob pattern like '*@company.com'.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			cmd.SilenceUsage = true
+			if err := output.Validate(opts.Output, formats...); err != nil {
+				return err
+			}
+			if err := opts.Filter.Validate(); err != nil {
+				return err
+			}
 			opts.Streams = userio.NewIOStreams(
 				cmd.InOrStdin(),
 				cmd.OutOrStdout(),
@@ -31,14 +55,72 @@ func NewTenantListCmd(cfg *config.Config) *cobra.Command {
 		},
 	}
 
+	output.RegisterFlag(tenantListCmd, &opts.Output, formats...)
+	tenantListCmd.Flags().StringSliceVar(
+		&opts.Columns,
+		"columns",
+		nil,
+		"Columns to list, among: "+strings.Join(corectltnt.ColumnNames(), ", "),
+	)
+	_ = tenantListCmd.RegisterFlagCompletionFunc("columns", cobra.FixedCompletions(corectltnt.ColumnNames(), cobra.ShellCompDirectiveNoFileComp))
+	tenantListCmd.Flags().StringVar(
+		&opts.Filter.Kind,
+		"kind",
+		"",
+		"List only the tenants of the kind: OrgUnit or DeliveryUnit",
+	)
+	_ = tenantListCmd.RegisterFlagCompletionFunc("kind", cobra.FixedCompletions([]string{"OrgUnit", "DeliveryUnit"}, cobra.ShellCompDirectiveNoFileComp))
+	tenantListCmd.Flags().StringVar(
+		&opts.Filter.Owner,
+		"owner",
+		"",
+		"List only the tenants owned by the org unit",
+	)
+	tenantListCmd.Flags().StringVar(
+		&opts.Filter.Type,
+		"type",
+		"",
+		"List only the delivery units of the type",
+	)
+	tenantListCmd.Flags().StringVar(
+		&opts.Filter.Environment,
+		"environment",
+		"",
+		"List only the tenants with the environment",
+	)
+	tenantListCmd.Flags().BoolVar(
+		&opts.Filter.RepoMissing,
+		"repo-missing",
+		false,
+		"List only the delivery units without a repository",
+	)
+	tenantListCmd.Flags().StringVar(
+		&opts.Filter.ContactEmail,
+		"contact-email",
+		"",
+		"List only the tenants with a contact email matching the glob pattern",
+	)
+
 	config.RegisterBoolParameterAsFlag(&cfg.Repositories.AllowDirty, tenantListCmd.Flags())
 
 	return tenantListCmd
 }
 
 func run(opts *TenantListOpts, cfg *config.Config) error {
+	columnNames := opts.Columns
+	if len(columnNames) == 0 {
+		columnNames = corectltnt.DefaultColumns
+		if opts.Output != output.Table {
+			columnNames = corectltnt.ColumnNames()
+		}
+	}
+	columns, err := corectltnt.SelectColumns(columnNames)
+	if err != nil {
+		return err
+	}
+
 	repoParams := []config.Parameter[string]{cfg.Repositories.CPlatform}
-	err := config.Update(cfg.GitHub.Token.Value, opts.Streams, cfg.Repositories.AllowDirty.Value, repoParams)
+	err = config.Update(cfg.GitHub.Token.Value, opts.Streams, cfg.Repositories.AllowDirty.Value, repoParams)
 	if err != nil {
 		return fmt.Errorf("failed to update config repos: %w", err)
 	}
@@ -47,11 +129,36 @@ func run(opts *TenantListOpts, cfg *config.Config) error {
 	if err != nil {
 		return fmt.Errorf("failed to list tenants: %w", err)
 	}
+	return render(opts, opts.Filter.Apply(tenants), columns)
+}
 
-	table := corectltnt.NewTable(opts.Streams)
-	for _, t := range tenants {
-		table.AppendRow(t)
+func render(opts *TenantListOpts, tenants []tenant.Tenant, columns []corectltnt.Column) error {
+	switch opts.Output {
+	case output.JSON, output.YAML:
+		records := make([]corectltnt.Fields, len(tenants))
+		for i, t := range tenants {
+			records[i] = corectltnt.Record(t, columns)
+		}
+		return output.Encode(opts.Streams.GetOutput(), opts.Output, records)
+	case output.CSV:
+		header := make([]string, len(columns))
+		for i, c := range columns {
+			header[i] = c.Name
+		}
+		rows := make([][]string, len(tenants))
+		for i, t := range tenants {
+			rows[i] = make([]string, len(columns))
+			for j, c := range columns {
+				rows[i][j] = c.Text(t)
+			}
+		}
+		return output.WriteCSV(opts.Streams.GetOutput(), header, rows)
+	default:
+		table := corectltnt.NewTableWithColumns(opts.Streams, columns)
+		for _, t := range tenants {
+			table.AppendRow(t)
+		}
+		table.Render()
+		return nil
 	}
-	table.Render()
-	return nil
 }
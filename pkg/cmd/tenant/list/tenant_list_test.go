@@ -0,0 +1,43 @@
+package list
+
+import (
+	"bytes"
+	"testing"
+
+	coretnt "github.com/coreeng/core-platform/pkg/tenant"
+	"github.com/coreeng/corectl/pkg/cmdutil/output"
+	"github.com/coreeng/corectl/pkg/cmdutil/userio"
+	corectltnt "github.com/coreeng/corectl/pkg/tenant"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var tenants = []coretnt.Tenant{
+	{Name: "du1", Kind: "DeliveryUnit", Owner: "ou1", Environments: []string{"dev", "prod"}},
+}
+
+func renderWith(t *testing.T, format string, columnNames []string) string {
+	var stdin, stdout, stderr bytes.Buffer
+	opts := &TenantListOpts{Output: format, Streams: userio.NewIOStreams(&stdin, &stdout, &stderr)}
+	columns, err := corectltnt.SelectColumns(columnNames)
+	require.NoError(t, err)
+	require.NoError(t, render(opts, tenants, columns))
+	return stdout.String()
+}
+
+func TestRenderCSV(t *testing.T) {
+	assert.Equal(t, "name,owner,environments\ndu1,ou1,\"dev,prod\"\n", renderWith(t, output.CSV, []string{"name", "owner", "environments"}))
+}
+
+func TestRenderJSON(t *testing.T) {
+	assert.JSONEq(t, `[{"name": "du1", "environments": ["dev", "prod"]}]`, renderWith(t, output.JSON, []string{"name", "environments"}))
+}
+
+func TestRenderJSONInColumnsOrder(t *testing.T) {
+	assert.Equal(t, "[\n  {\n    \"owner\": \"ou1\",\n    \"name\": \"du1\"\n  }\n]\n", renderWith(t, output.JSON, []string{"owner", "name"}))
+}
+
+func TestRenderYAML(t *testing.T) {
+	assert.Equal(t, "- name: du1\n  kind: DeliveryUnit\n", renderWith(t, output.YAML, []string{"name", "kind"}))
+	assert.Equal(t, "- environments:\n    - dev\n    - prod\n  name: du1\n", renderWith(t, output.YAML, []string{"environments", "name"}))
+}
@@ -0,0 +1,177 @@
+package move
+
+import (
+	"fmt"
+
+	coretnt "github.com/coreeng/core-platform/pkg/tenant"
+	"github.com/coreeng/corectl/pkg/cmdutil/config"
+	"github.com/coreeng/corectl/pkg/cmdutil/configpath"
+	"github.com/coreeng/corectl/pkg/cmdutil/userio"
+	"github.com/coreeng/corectl/pkg/git"
+	"github.com/coreeng/corectl/pkg/logger"
+	"github.com/coreeng/corectl/pkg/tenant"
+	"github.com/google/go-github/v60/github"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+type TenantMoveOpts struct {
+	DeliveryUnit string
+	OrgUnit      string
+	Inherit      bool
+	DryRun       bool
+
+	Streams userio.IOStreams
+}
+
+func NewTenantMoveCmd(cfg *config.Config) *cobra.Command {
+	opts := TenantMoveOpts{}
+	tenantMoveCmd := &cobra.Command{
+		Use:   "move <delivery-unit> --to <org-unit>",
+		Short: "Move a delivery unit to another org unit",
+		Long: `This command moves a delivery unit to another org unit through a PR to the platform repository.
+
+The owner of the delivery unit is changed and its definition moved under the new org unit.
+With --inherit, the delivery unit takes the admin and readonly groups and the environments of the new org unit,
+as a delivery unit created for it does.
+The diff of the definition is shown, and the moved delivery unit is validated along with all the other tenants
+before the PR is raised.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+			opts.DeliveryUnit = args[0]
+			opts.Streams = userio.NewIOStreams(
+				cmd.InOrStdin(),
+				cmd.OutOrStdout(),
+				cmd.OutOrStderr(),
+			)
+			return run(&opts, cfg)
+		},
+	}
+
+	tenantMoveCmd.Flags().StringVar(
+		&opts.OrgUnit,
+		"to",
+		"",
+		"Org unit to move the delivery unit to",
+	)
+	_ = tenantMoveCmd.MarkFlagRequired("to")
+	tenantMoveCmd.Flags().BoolVar(
+		&opts.Inherit,
+		"inherit",
+		false,
+		"Inherit the admin and readonly groups and the environments of the new org unit",
+	)
+	tenantMoveCmd.Flags().BoolVarP(
+		&opts.DryRun,
+		"dry-run",
+		"n",
+		false,
+		"Dry run",
+	)
+
+	config.RegisterStringParameterAsFlag(&cfg.GitHub.Token, tenantMoveCmd.Flags())
+	config.RegisterBoolParameterAsFlag(&cfg.Repositories.AllowDirty, tenantMoveCmd.Flags())
+
+	return tenantMoveCmd
+}
+
+func run(opts *TenantMoveOpts, cfg *config.Config) error {
+	repoParams := []config.Parameter[string]{cfg.Repositories.CPlatform}
+	err := config.Update(cfg.GitHub.Token.Value, opts.Streams, cfg.Repositories.AllowDirty.Value, repoParams)
+	if err != nil {
+		return fmt.Errorf("failed to update config repos: %w", err)
+	}
+
+	tenantsDir := configpath.GetCorectlCPlatformDir("tenants")
+	du, err := coretnt.FindByName(tenantsDir, opts.DeliveryUnit)
+	if err != nil {
+		return fmt.Errorf("failed to find the tenant: %w", err)
+	}
+	if du == nil {
+		return fmt.Errorf("tenant is not found: %s", opts.DeliveryUnit)
+	}
+	ou, err := coretnt.FindByName(tenantsDir, opts.OrgUnit)
+	if err != nil {
+		return fmt.Errorf("failed to find the tenant: %w", err)
+	}
+	if ou == nil {
+		return fmt.Errorf("tenant is not found: %s", opts.OrgUnit)
+	}
+
+	moved, err := moveDeliveryUnit(du, ou, opts.Inherit)
+	if err != nil {
+		return err
+	}
+	diff, err := tenant.Diff(du, moved)
+	if err != nil {
+		return fmt.Errorf("failed to compare the tenant definitions: %w", err)
+	}
+	opts.Streams.Print(diff)
+
+	existingTenants, err := coretnt.List(tenantsDir)
+	if err != nil {
+		return err
+	}
+	if err := tenant.Validate(moved, existingTenants); err != nil {
+		return fmt.Errorf("invalid tenant %s: %w", moved.Name, err)
+	}
+
+	githubClient := github.NewClient(nil).
+		WithAuthToken(cfg.GitHub.Token.Value)
+	gitAuth := git.UrlTokenAuthMethod(cfg.GitHub.Token.Value)
+
+	logger.Warn().Msgf("Moving delivery unit %s from %s to %s in platform repository: %s", du.Name, du.Owner, ou.Name, cfg.Repositories.CPlatform.Value)
+	result, err := tenant.Move(&tenant.MoveOp{
+		Tenant:            moved,
+		From:              du,
+		OwnerTenant:       ou,
+		CplatformRepoPath: configpath.GetCorectlCPlatformDir(),
+		BranchName:        fmt.Sprintf("move-tenant-%s-to-%s", du.Name, ou.Name),
+		CommitMessage:     fmt.Sprintf("Move delivery unit %s to org unit %s", du.Name, ou.Name),
+		PRName:            fmt.Sprintf("Move delivery unit %s to org unit %s", du.Name, ou.Name),
+		PRBody:            fmt.Sprintf("Moves delivery unit '%s' from org unit '%s' to '%s':\n\n```diff\n%s```", du.Name, du.Owner, ou.Name, diff),
+		GitAuth:           gitAuth,
+		DryRun:            opts.DryRun,
+	}, githubClient)
+	if err != nil {
+		return fmt.Errorf("failed to move tenant: %w", err)
+	}
+
+	logger.Warn().Msgf("Created PR to move delivery unit %s: %s", du.Name, result.PRUrl)
+	return nil
+}
+
+// moveDeliveryUnit returns a copy of the delivery unit owned by the org unit, which isn't saved yet.
+// When inheriting, the copy takes the groups and the environments of the org unit.
+func moveDeliveryUnit(du *coretnt.Tenant, ou *coretnt.Tenant, inherit bool) (*coretnt.Tenant, error) {
+	if du.Kind != "DeliveryUnit" {
+		return nil, fmt.Errorf("cannot move '%s': only delivery units can be moved", du.Name)
+	}
+	if ou.Kind != "OrgUnit" {
+		return nil, fmt.Errorf("cannot move '%s' to '%s': it is not an org unit", du.Name, ou.Name)
+	}
+	if du.Owner == ou.Name {
+		return nil, fmt.Errorf("delivery unit '%s' is already owned by org unit '%s'", du.Name, ou.Name)
+	}
+
+	// The copy is decoded from the definition, so it is saved under the new owner rather than at the previous path
+	definition, err := yaml.Marshal(du)
+	if err != nil {
+		return nil, err
+	}
+	moved := &coretnt.Tenant{}
+	if err := yaml.Unmarshal(definition, moved); err != nil {
+		return nil, err
+	}
+
+	moved.Owner = ou.Name
+	if inherit {
+		moved.Environments = ou.Environments
+		moved.AdminGroup = ou.AdminGroup
+		moved.ReadOnlyGroup = ou.ReadOnlyGroup
+		moved.ProdAdminGroup = ou.ProdAdminGroup
+		moved.ProdReadOnlyGroup = ou.ProdReadOnlyGroup
+	}
+	return moved, nil
+}
@@ -0,0 +1,80 @@
+package move
+
+import (
+	"testing"
+
+	coretnt "github.com/coreeng/core-platform/pkg/tenant"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func deliveryUnit() *coretnt.Tenant {
+	return &coretnt.Tenant{
+		Name:          "du",
+		Kind:          "DeliveryUnit",
+		Type:          "application",
+		Owner:         "old-ou",
+		Description:   "Delivery unit",
+		ContactEmail:  "du@company.com",
+		Environments:  []string{"dev"},
+		Repo:          "https://github.com/org/du",
+		AdminGroup:    "du-admins",
+		ReadOnlyGroup: "du-readers",
+		CloudAccess:   []coretnt.CloudAccess{},
+	}
+}
+
+var newOrgUnit = &coretnt.Tenant{
+	Name:           "new-ou",
+	Kind:           "OrgUnit",
+	Environments:   []string{"dev", "prod"},
+	AdminGroup:     "ou-admins",
+	ReadOnlyGroup:  "ou-readers",
+	ProdAdminGroup: "ou-prod-admins",
+}
+
+func TestMoveDeliveryUnitKeepsItsFields(t *testing.T) {
+	du := deliveryUnit()
+
+	moved, err := moveDeliveryUnit(du, newOrgUnit, false)
+
+	require.NoError(t, err)
+	expected := deliveryUnit()
+	expected.Owner = "new-ou"
+	assert.Equal(t, expected, moved)
+	assert.Nil(t, moved.SavedPath())
+	assert.Equal(t, "old-ou", du.Owner, "the original delivery unit is left unchanged")
+}
+
+func TestMoveDeliveryUnitInheritsFromOrgUnit(t *testing.T) {
+	moved, err := moveDeliveryUnit(deliveryUnit(), newOrgUnit, true)
+
+	require.NoError(t, err)
+	assert.Equal(t, "new-ou", moved.Owner)
+	assert.Equal(t, []string{"dev", "prod"}, moved.Environments)
+	assert.Equal(t, "ou-admins", moved.AdminGroup)
+	assert.Equal(t, "ou-readers", moved.ReadOnlyGroup)
+	assert.Equal(t, "ou-prod-admins", moved.ProdAdminGroup)
+	assert.Equal(t, "du@company.com", moved.ContactEmail)
+}
+
+func TestMoveDeliveryUnitRejectsOrgUnit(t *testing.T) {
+	_, err := moveDeliveryUnit(newOrgUnit, newOrgUnit, false)
+
+	assert.ErrorContains(t, err, "only delivery units can be moved")
+}
+
+func TestMoveDeliveryUnitRejectsDeliveryUnitTarget(t *testing.T) {
+	_, err := moveDeliveryUnit(deliveryUnit(), deliveryUnit(), false)
+
+	assert.ErrorContains(t, err, "it is not an org unit")
+}
+
+func TestMoveDeliveryUnitRejectsSameOwner(t *testing.T) {
+	du := deliveryUnit()
+	du.Owner = newOrgUnit.Name
+
+	_, err := moveDeliveryUnit(du, newOrgUnit, false)
+
+	assert.ErrorContains(t, err, "already owned by org unit 'new-ou'")
+}
@@ -2,10 +2,13 @@ package tenant
 
 import (
 	"github.com/coreeng/corectl/pkg/cmd/tenant/create"
+	"github.com/coreeng/corectl/pkg/cmd/tenant/delete"
 	"github.com/coreeng/corectl/pkg/cmd/tenant/describe"
 	"github.com/coreeng/corectl/pkg/cmd/tenant/list"
+	"github.com/coreeng/corectl/pkg/cmd/tenant/move"
 	"github.com/coreeng/corectl/pkg/cmd/tenant/setrepo"
 	"github.com/coreeng/corectl/pkg/cmd/tenant/tree"
+	"github.com/coreeng/corectl/pkg/cmd/tenant/update"
 	"github.com/coreeng/corectl/pkg/cmdutil/config"
 	"github.com/spf13/cobra"
 )
@@ -28,6 +31,9 @@ func NewTenantCmd(cfg *config.Config) *cobra.Command {
 	tenantCmd.AddCommand(describe.NewTenantDescribeCmd(cfg))
 	tenantCmd.AddCommand(setrepo.NewTenantSetRepoCmd(cfg))
 	tenantCmd.AddCommand(create.NewTenantCreateCmd(cfg))
+	tenantCmd.AddCommand(update.NewTenantUpdateCmd(cfg))
+	tenantCmd.AddCommand(delete.NewTenantDeleteCmd(cfg))
+	tenantCmd.AddCommand(move.NewTenantMoveCmd(cfg))
 
 	return tenantCmd
 }
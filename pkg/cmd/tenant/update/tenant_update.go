@@ -0,0 +1,391 @@
+package update
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/coreeng/core-platform/pkg/environment"
+	coretnt "github.com/coreeng/core-platform/pkg/tenant"
+	"github.com/coreeng/corectl/pkg/cmdutil/config"
+	"github.com/coreeng/corectl/pkg/cmdutil/configpath"
+	"github.com/coreeng/corectl/pkg/cmdutil/userio"
+	"github.com/coreeng/corectl/pkg/cmdutil/userio/confirmation"
+	"github.com/coreeng/corectl/pkg/git"
+	"github.com/coreeng/corectl/pkg/logger"
+	"github.com/coreeng/corectl/pkg/tenant"
+	"github.com/google/go-github/v60/github"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+)
+
+type TenantUpdateOpts struct {
+	TenantName      string
+	Values          map[string]*string
+	Environments    []string
+	CloudAccessFile string
+	NonInteractive  bool
+	DryRun          bool
+
+	// Changed reports whether the flag was given, only the fields of the flags given are updated
+	Changed func(flag string) bool
+
+	Streams userio.IOStreams
+}
+
+// stringField is a mutable string field of a tenant, updated with its flag or in the wizard.
+type stringField struct {
+	flag  string
+	field string
+	usage string
+	// optional fields can be cleared
+	optional         bool
+	deliveryUnitOnly bool
+	value            func(t *coretnt.Tenant) *string
+}
+
+var stringFields = []stringField{
+	{flag: "description", field: "Description", usage: "Description of the tenant",
+		value: func(t *coretnt.Tenant) *string { return &t.Description }},
+	{flag: "contact-email", field: "ContactEmail", usage: "Contact email of the tenant",
+		value: func(t *coretnt.Tenant) *string { return &t.ContactEmail }},
+	{flag: "cost-centre", field: "CostCentre", usage: "Cost centre of the tenant", optional: true,
+		value: func(t *coretnt.Tenant) *string { return &t.CostCentre }},
+	{flag: "prefix", field: "Prefix", usage: "Hierarchy prefix (e.g. area/subarea)", optional: true,
+		value: func(t *coretnt.Tenant) *string { return &t.Prefix }},
+	{flag: "admin-group", field: "AdminGroup", usage: "Admin group of the tenant",
+		value: func(t *coretnt.Tenant) *string { return &t.AdminGroup }},
+	{flag: "readonly-group", field: "ReadOnlyGroup", usage: "Readonly group of the tenant",
+		value: func(t *coretnt.Tenant) *string { return &t.ReadOnlyGroup }},
+	{flag: "prod-admin-group", field: "ProdAdminGroup", usage: "Admin group of the tenant in production", optional: true,
+		value: func(t *coretnt.Tenant) *string { return &t.ProdAdminGroup }},
+	{flag: "prod-readonly-group", field: "ProdReadOnlyGroup", usage: "Readonly group of the tenant in production", optional: true,
+		value: func(t *coretnt.Tenant) *string { return &t.ProdReadOnlyGroup }},
+	{flag: "repo", field: "Repo", usage: "Repository of the delivery unit", optional: true, deliveryUnitOnly: true,
+		value: func(t *coretnt.Tenant) *string { return &t.Repo }},
+}
+
+const (
+	environmentsFlag    = "environments"
+	cloudAccessFileFlag = "cloud-access-file"
+)
+
+func NewTenantUpdateCmd(cfg *config.Config) *cobra.Command {
+	opts := TenantUpdateOpts{Values: map[string]*string{}}
+	tenantUpdateCmd := &cobra.Command{
+		Use:   "update <tenant-name>",
+		Short: "Update an org unit or a delivery unit",
+		Long: `This command updates the definition of an org unit or a delivery unit through a PR to the platform repository.
+
+Only the fields of the flags given are updated, a flag given an empty value clears an optional field.
+Without any flag, a wizard prompts for each field, starting from its current value, and for a file to replace
+the cloud access with.
+The diff of the definition is shown, and the updated tenant is validated along with all the others before the PR is raised.
+
+The cloud access of a tenant is replaced with the list defined in the YAML file given with --` + cloudAccessFileFlag + `.
+The owner of a delivery unit is changed with tenant move.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+			nonInteractive, err := cmd.Flags().GetBool("non-interactive")
+			if err != nil {
+				logger.Panic().With(zap.Error(err)).Msg("could not get non-interactive flag")
+			}
+			opts.NonInteractive = nonInteractive
+			opts.TenantName = args[0]
+			opts.Changed = cmd.Flags().Changed
+			opts.Streams = userio.NewIOStreamsWithInteractive(
+				cmd.InOrStdin(),
+				cmd.OutOrStdout(),
+				cmd.OutOrStderr(),
+				!opts.NonInteractive,
+			)
+			return run(&opts, cfg)
+		},
+	}
+
+	for _, f := range stringFields {
+		opts.Values[f.flag] = new(string)
+		tenantUpdateCmd.Flags().StringVar(opts.Values[f.flag], f.flag, "", f.usage)
+	}
+	tenantUpdateCmd.Flags().StringSliceVar(
+		&opts.Environments,
+		environmentsFlag,
+		nil,
+		"Environments available to the tenant",
+	)
+	tenantUpdateCmd.Flags().StringVar(
+		&opts.CloudAccessFile,
+		cloudAccessFileFlag,
+		"",
+		"YAML file with the list of cloud access of the tenant",
+	)
+	tenantUpdateCmd.Flags().BoolVarP(
+		&opts.DryRun,
+		"dry-run",
+		"n",
+		false,
+		"Dry run",
+	)
+
+	config.RegisterStringParameterAsFlag(&cfg.GitHub.Token, tenantUpdateCmd.Flags())
+	config.RegisterBoolParameterAsFlag(&cfg.Repositories.AllowDirty, tenantUpdateCmd.Flags())
+
+	return tenantUpdateCmd
+}
+
+func run(opts *TenantUpdateOpts, cfg *config.Config) error {
+	repoParams := []config.Parameter[string]{cfg.Repositories.CPlatform}
+	err := config.Update(cfg.GitHub.Token.Value, opts.Streams, cfg.Repositories.AllowDirty.Value, repoParams)
+	if err != nil {
+		return fmt.Errorf("failed to update config repos: %w", err)
+	}
+
+	tenantsDir := configpath.GetCorectlCPlatformDir("tenants")
+	t, err := coretnt.FindByName(tenantsDir, opts.TenantName)
+	if err != nil {
+		return fmt.Errorf("failed to find the tenant: %w", err)
+	}
+	if t == nil {
+		return fmt.Errorf("tenant is not found: %s", opts.TenantName)
+	}
+	ownerTenant, err := findOwner(tenantsDir, t)
+	if err != nil {
+		return err
+	}
+	envs, err := environment.List(configpath.GetCorectlCPlatformDir("environments"))
+	if err != nil {
+		return err
+	}
+	envNames := make([]string, len(envs))
+	for i, env := range envs {
+		envNames[i] = env.Environment
+	}
+
+	before := *t
+	wizard := !opts.anyChanged()
+	if wizard {
+		if !opts.Streams.IsInteractive() {
+			return errors.New("nothing to update, pass the flags of the fields to update")
+		}
+		err = opts.promptFields(t, envNames)
+	} else {
+		err = opts.applyFlags(t, envNames)
+	}
+	if err != nil {
+		return err
+	}
+
+	diff, err := tenant.Diff(&before, t)
+	if err != nil {
+		return fmt.Errorf("failed to compare the tenant definitions: %w", err)
+	}
+	if diff == "" {
+		opts.Streams.Info(fmt.Sprintf("tenant %s is unchanged", t.Name))
+		return nil
+	}
+	opts.Streams.Print(diff)
+
+	existingTenants, err := coretnt.List(tenantsDir)
+	if err != nil {
+		return err
+	}
+	if err := tenant.Validate(t, existingTenants); err != nil {
+		return fmt.Errorf("invalid tenant %s: %w", t.Name, err)
+	}
+
+	if wizard {
+		confirmed, err := confirmation.GetInput(opts.Streams, fmt.Sprintf("Raise a PR to update tenant %s?", t.Name))
+		if err != nil {
+			return err
+		}
+		if !confirmed {
+			return errors.New("update cancelled")
+		}
+	}
+
+	githubClient := github.NewClient(nil).
+		WithAuthToken(cfg.GitHub.Token.Value)
+	gitAuth := git.UrlTokenAuthMethod(cfg.GitHub.Token.Value)
+
+	logger.Warn().Msgf("Updating tenant %s in platform repository: %s", t.Name, cfg.Repositories.CPlatform.Value)
+	result, err := tenant.CreateOrUpdate(&tenant.CreateOrUpdateOp{
+		Tenant:            t,
+		OwnerTenant:       ownerTenant,
+		CplatformRepoPath: configpath.GetCorectlCPlatformDir(),
+		BranchName:        fmt.Sprintf("update-tenant-%s", t.Name),
+		CommitMessage:     fmt.Sprintf("Update tenant %s", t.Name),
+		PRName:            fmt.Sprintf("Update tenant %s", t.Name),
+		PRBody:            fmt.Sprintf("Updates tenant '%s':\n\n```diff\n%s```", t.Name, diff),
+		GitAuth:           gitAuth,
+		DryRun:            opts.DryRun,
+	}, githubClient)
+	if err != nil {
+		return fmt.Errorf("failed to update tenant: %w", err)
+	}
+
+	logger.Warn().Msgf("Created PR to update tenant %s: %s", t.Name, result.PRUrl)
+	return nil
+}
+
+// findOwner returns the tenant the definition of the tenant is saved under: the root for org units,
+// and the owner org unit for delivery units.
+func findOwner(tenantsDir string, t *coretnt.Tenant) (*coretnt.Tenant, error) {
+	if t.Kind == "OrgUnit" {
+		return coretnt.RootTenant(tenantsDir), nil
+	}
+	ownerOU, err := coretnt.FindByName(tenantsDir, t.Owner)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve owner org unit %q for delivery unit %s: %w", t.Owner, t.Name, err)
+	}
+	if ownerOU == nil {
+		return nil, fmt.Errorf("owner org unit %q not found for delivery unit %s", t.Owner, t.Name)
+	}
+	return ownerOU, nil
+}
+
+func (opts *TenantUpdateOpts) anyChanged() bool {
+	for _, f := range stringFields {
+		if opts.Changed(f.flag) {
+			return true
+		}
+	}
+	return opts.Changed(environmentsFlag) || opts.Changed(cloudAccessFileFlag)
+}
+
+// applyFlags updates the fields of the tenant for which a flag was given.
+func (opts *TenantUpdateOpts) applyFlags(t *coretnt.Tenant, envNames []string) error {
+	for _, f := range stringFields {
+		if !opts.Changed(f.flag) {
+			continue
+		}
+		if f.deliveryUnitOnly && t.Kind != "DeliveryUnit" {
+			return fmt.Errorf("--%s can only be set for delivery units", f.flag)
+		}
+		value, err := f.validate(*opts.Values[f.flag])
+		if err != nil {
+			return fmt.Errorf("invalid --%s: %w", f.flag, err)
+		}
+		*f.value(t) = value
+	}
+	if opts.Changed(environmentsFlag) {
+		envs, err := validateEnvironments(opts.Environments, envNames)
+		if err != nil {
+			return fmt.Errorf("invalid --%s: %w", environmentsFlag, err)
+		}
+		t.Environments = envs
+	}
+	if opts.Changed(cloudAccessFileFlag) {
+		cloudAccess, err := readCloudAccess(opts.CloudAccessFile)
+		if err != nil {
+			return err
+		}
+		t.CloudAccess = cloudAccess
+	}
+	return nil
+}
+
+// promptFields asks for each field of the tenant, starting from its current value.
+func (opts *TenantUpdateOpts) promptFields(t *coretnt.Tenant, envNames []string) error {
+	for _, f := range stringFields {
+		if f.deliveryUnitOnly && t.Kind != "DeliveryUnit" {
+			continue
+		}
+		prompt := fmt.Sprintf("%s:", f.usage)
+		if f.optional {
+			prompt = fmt.Sprintf("%s (optional):", f.usage)
+		}
+		input := &userio.TextInput[string]{
+			Prompt:         prompt,
+			InitialValue:   *f.value(t),
+			ValidateAndMap: f.validate,
+		}
+		value, err := input.GetInput(opts.Streams)
+		if err != nil {
+			return err
+		}
+		*f.value(t) = value
+	}
+
+	envsInput := &userio.TextInput[[]string]{
+		Prompt:       fmt.Sprintf("Environments (comma separated, one of %s):", strings.Join(envNames, ", ")),
+		InitialValue: strings.Join(t.Environments, ","),
+		ValidateAndMap: func(inp string) ([]string, error) {
+			return validateEnvironments(strings.Split(inp, ","), envNames)
+		},
+	}
+	envs, err := envsInput.GetInput(opts.Streams)
+	if err != nil {
+		return err
+	}
+	t.Environments = envs
+
+	cloudAccessInput := &userio.TextInput[[]coretnt.CloudAccess]{
+		Prompt:         "YAML file with the list of cloud access (optional, keeps the current cloud access when empty):",
+		Placeholder:    "./cloud-access.yaml",
+		ValidateAndMap: cloudAccessFromFile(t.CloudAccess),
+	}
+	cloudAccess, err := cloudAccessInput.GetInput(opts.Streams)
+	if err != nil {
+		return err
+	}
+	t.CloudAccess = cloudAccess
+	return nil
+}
+
+// cloudAccessFromFile reads the cloud access from the file entered, keeping the current cloud access when none is.
+func cloudAccessFromFile(current []coretnt.CloudAccess) userio.ValidateTextAndMapFn[[]coretnt.CloudAccess] {
+	return func(inp string) ([]coretnt.CloudAccess, error) {
+		inp = strings.TrimSpace(inp)
+		if inp == "" {
+			return current, nil
+		}
+		return readCloudAccess(inp)
+	}
+}
+
+func (f stringField) validate(inp string) (string, error) {
+	inp = strings.TrimSpace(inp)
+	if inp == "" && f.optional {
+		return inp, nil
+	}
+	t := &coretnt.Tenant{}
+	*f.value(t) = inp
+	if err := t.ValidateField(f.field); err != nil {
+		return "", err
+	}
+	return inp, nil
+}
+
+func validateEnvironments(inp []string, envNames []string) ([]string, error) {
+	envs := []string{}
+	for _, env := range inp {
+		env = strings.TrimSpace(env)
+		if env == "" {
+			continue
+		}
+		if !slices.Contains(envNames, env) {
+			return nil, fmt.Errorf("unknown environment: %s", env)
+		}
+		envs = append(envs, env)
+	}
+	if len(envs) == 0 {
+		return nil, fmt.Errorf("at least one environment must be selected")
+	}
+	return envs, nil
+}
+
+func readCloudAccess(path string) ([]coretnt.CloudAccess, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cloud access: %w", err)
+	}
+	cloudAccess := []coretnt.CloudAccess{}
+	if err := yaml.Unmarshal(content, &cloudAccess); err != nil {
+		return nil, fmt.Errorf("failed to parse cloud access %s: %w", path, err)
+	}
+	return cloudAccess, nil
+}
@@ -0,0 +1,119 @@
+package update
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	coretnt "github.com/coreeng/core-platform/pkg/tenant"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func optsWithFlags(flags map[string]string) *TenantUpdateOpts {
+	opts := &TenantUpdateOpts{Values: map[string]*string{}}
+	for _, f := range stringFields {
+		value := flags[f.flag]
+		opts.Values[f.flag] = &value
+	}
+	opts.Changed = func(flag string) bool {
+		_, ok := flags[flag]
+		return ok
+	}
+	return opts
+}
+
+func TestApplyFlagsOnlyUpdatesGivenFields(t *testing.T) {
+	tnt := &coretnt.Tenant{
+		Name:         "ou",
+		Kind:         "OrgUnit",
+		Description:  "Old description",
+		ContactEmail: "old@company.com",
+		CostCentre:   "cc-1",
+		Environments: []string{"dev"},
+	}
+	opts := optsWithFlags(map[string]string{"contact-email": " new@company.com ", "cost-centre": ""})
+
+	require.NoError(t, opts.applyFlags(tnt, []string{"dev", "prod"}))
+
+	assert.Equal(t, "Old description", tnt.Description)
+	assert.Equal(t, "new@company.com", tnt.ContactEmail)
+	assert.Empty(t, tnt.CostCentre)
+	assert.Equal(t, []string{"dev"}, tnt.Environments)
+}
+
+func TestApplyFlagsUpdatesEnvironments(t *testing.T) {
+	tnt := &coretnt.Tenant{Name: "ou", Kind: "OrgUnit", Environments: []string{"dev"}}
+	opts := optsWithFlags(map[string]string{environmentsFlag: ""})
+	opts.Environments = []string{"dev", "prod"}
+
+	require.NoError(t, opts.applyFlags(tnt, []string{"dev", "prod"}))
+
+	assert.Equal(t, []string{"dev", "prod"}, tnt.Environments)
+}
+
+func TestApplyFlagsRejectsUnknownEnvironment(t *testing.T) {
+	tnt := &coretnt.Tenant{Name: "ou", Kind: "OrgUnit"}
+	opts := optsWithFlags(map[string]string{environmentsFlag: ""})
+	opts.Environments = []string{"staging"}
+
+	err := opts.applyFlags(tnt, []string{"dev", "prod"})
+
+	assert.ErrorContains(t, err, "unknown environment: staging")
+}
+
+func TestApplyFlagsRejectsRepoForOrgUnit(t *testing.T) {
+	tnt := &coretnt.Tenant{Name: "ou", Kind: "OrgUnit"}
+	opts := optsWithFlags(map[string]string{"repo": "https://github.com/org/repo"})
+
+	err := opts.applyFlags(tnt, nil)
+
+	assert.ErrorContains(t, err, "--repo can only be set for delivery units")
+}
+
+func TestApplyFlagsReplacesCloudAccess(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cloud-access.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+- name: bucket-access
+  provider: gcp
+  environment: dev
+  kubernetesServiceAccounts:
+    - du-functional/du
+`), 0o600))
+	tnt := &coretnt.Tenant{Name: "du", Kind: "DeliveryUnit", CloudAccess: []coretnt.CloudAccess{{Name: "old"}}}
+	opts := optsWithFlags(map[string]string{cloudAccessFileFlag: path})
+	opts.CloudAccessFile = path
+
+	require.NoError(t, opts.applyFlags(tnt, nil))
+
+	assert.Equal(t, []coretnt.CloudAccess{{
+		Name:                      "bucket-access",
+		Provider:                  "gcp",
+		Environment:               "dev",
+		KubernetesServiceAccounts: []string{"du-functional/du"},
+	}}, tnt.CloudAccess)
+}
+
+func TestCloudAccessFromFile(t *testing.T) {
+	current := []coretnt.CloudAccess{{Name: "old"}}
+	path := filepath.Join(t.TempDir(), "cloud-access.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("- name: bucket-access\n"), 0o600))
+	mapCloudAccess := cloudAccessFromFile(current)
+
+	cloudAccess, err := mapCloudAccess("  ")
+	require.NoError(t, err)
+	assert.Equal(t, current, cloudAccess)
+
+	cloudAccess, err = mapCloudAccess(path)
+	require.NoError(t, err)
+	assert.Equal(t, []coretnt.CloudAccess{{Name: "bucket-access"}}, cloudAccess)
+
+	_, err = mapCloudAccess(filepath.Join(t.TempDir(), "missing.yaml"))
+	assert.ErrorContains(t, err, "failed to read cloud access")
+}
+
+func TestAnyChanged(t *testing.T) {
+	assert.False(t, optsWithFlags(map[string]string{}).anyChanged())
+	assert.True(t, optsWithFlags(map[string]string{"prefix": ""}).anyChanged())
+	assert.True(t, optsWithFlags(map[string]string{environmentsFlag: ""}).anyChanged())
+}
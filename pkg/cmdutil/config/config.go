@@ -104,7 +104,12 @@ type Config struct {
 	GitHub       GitHubConfig       `yaml:"github"`
 	Repositories RepositoriesConfig `yaml:"repositories"`
 	P2P          P2PConfig          `yaml:"p2p"`
-	path         string
+	Kubernetes   KubernetesConfig   `yaml:"kubernetes"`
+	// Environments overrides the settings derived from the environment definitions, by environment name
+	Environments map[string]EnvironmentConfig `yaml:"environments,omitempty"`
+	// Resources are extra resources opened by `env open`, replacing the ones with the same name
+	Resources []ResourceConfig `yaml:"resources,omitempty"`
+	path      string
 }
 
 type GitHubConfig struct {
@@ -128,6 +133,30 @@ type P2PStageConfig struct {
 	DefaultEnvs Parameter[[]string] `yaml:"default-envs"`
 }
 
+type KubernetesConfig struct {
+	IsolatedKubeconfig Parameter[bool] `yaml:"isolated-kubeconfig"`
+}
+
+type EnvironmentConfig struct {
+	Bastion BastionConfig `yaml:"bastion,omitempty"`
+	// ProxyPort pins the local port of the proxy of the environment
+	ProxyPort int `yaml:"proxy-port,omitempty"`
+}
+
+type BastionConfig struct {
+	Instance  string `yaml:"instance,omitempty"`
+	Zone      string `yaml:"zone,omitempty"`
+	Interface string `yaml:"interface,omitempty"`
+	Port      int    `yaml:"port,omitempty"`
+}
+
+type ResourceConfig struct {
+	Name        string `yaml:"name"`
+	Description string `yaml:"description,omitempty"`
+	URL         string `yaml:"url"`
+	Tenant      bool   `yaml:"tenant,omitempty"`
+}
+
 func NewConfig() *Config {
 	return &Config{
 		GitHub: GitHubConfig{
@@ -157,6 +186,13 @@ func NewConfig() *Config {
 				help: "Allow local changes in configuration repositories",
 			},
 		},
+		Kubernetes: KubernetesConfig{
+			IsolatedKubeconfig: Parameter[bool]{
+				name: "Isolated kubeconfig",
+				flag: "isolated-kubeconfig",
+				help: "Write the cluster credentials to a kubeconfig file of the environment instead of the global kubeconfig",
+			},
+		},
 		path: "",
 	}
 }
@@ -45,6 +45,29 @@ var _ = Describe("config", func() {
 			Expect(newCfg).To(BeNil())
 		})
 
+		It("kubernetes value updated", func() {
+			newCfg, err := config.SetValue("kubernetes.isolated-kubeconfig", "false")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(newCfg.Kubernetes.IsolatedKubeconfig.Value).To(BeFalse())
+			Expect(config.Kubernetes.IsolatedKubeconfig.Value).To(BeTrue())
+		})
+
+		It("environment override updated", func() {
+			newCfg, err := config.SetValue("environments.us-dev.bastion.zone", "us-central1-b")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(newCfg.Environments["us-dev"].Bastion.Zone).To(Equal("us-central1-b"))
+			Expect(newCfg.Environments["us-dev"].Bastion.Port).To(Equal(8080))
+			Expect(config.Environments["us-dev"].Bastion.Zone).To(Equal("us-central1-a"))
+		})
+
+		It("environment proxy port updated", func() {
+			newCfg, err := config.SetValue("environments.us-dev.proxy-port", "36001")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(newCfg.Environments["us-dev"].ProxyPort).To(Equal(36001))
+			Expect(newCfg.Environments["us-dev"].Bastion.Zone).To(Equal("us-central1-a"))
+			Expect(config.Environments["us-dev"].ProxyPort).To(Equal(36000))
+		})
+
 		It("partial path", func() {
 			newCfg, err := config.SetValue("repositories", "random value")
 			Expect(err).To(HaveOccurred())
@@ -64,4 +87,10 @@ func fillCfgWithMockValues(cfg *Config) {
 	cfg.P2P.FastFeedback.DefaultEnvs.Value = []string{"dev"}
 	cfg.P2P.ExtendedTest.DefaultEnvs.Value = []string{"dev"}
 	cfg.P2P.Prod.DefaultEnvs.Value = []string{"prod"}
+
+	cfg.Kubernetes.IsolatedKubeconfig.Value = true
+
+	cfg.Environments = map[string]EnvironmentConfig{
+		"us-dev": {Bastion: BastionConfig{Zone: "us-central1-a", Port: 8080}, ProxyPort: 36000},
+	}
 }
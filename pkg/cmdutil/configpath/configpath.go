@@ -46,3 +46,21 @@ func GetCorectlTemplatesDir(paths ...string) string {
 	}
 	return baseDir
 }
+
+func GetCorectlProxiesDir(paths ...string) string {
+	baseDir := filepath.Join(GetCorectlHomeDir(), "proxies")
+	if len(paths) > 0 {
+		allPaths := append([]string{baseDir}, paths...)
+		return filepath.Join(allPaths...)
+	}
+	return baseDir
+}
+
+func GetCorectlKubeDir(paths ...string) string {
+	baseDir := filepath.Join(GetCorectlHomeDir(), "kube")
+	if len(paths) > 0 {
+		allPaths := append([]string{baseDir}, paths...)
+		return filepath.Join(allPaths...)
+	}
+	return baseDir
+}
@@ -0,0 +1,72 @@
+package output
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// Formats commands can print their output in, with -o/--output.
+const (
+	Table = "table"
+	JSON  = "json"
+	YAML  = "yaml"
+	CSV   = "csv"
+)
+
+// RegisterFlag registers the -o/--output flag choosing between the formats, the first one being the default.
+func RegisterFlag(cmd *cobra.Command, format *string, formats ...string) {
+	cmd.Flags().StringVarP(
+		format,
+		"output",
+		"o",
+		formats[0],
+		"Output format: "+strings.Join(formats, ", "),
+	)
+	_ = cmd.RegisterFlagCompletionFunc("output", cobra.FixedCompletions(formats, cobra.ShellCompDirectiveNoFileComp))
+}
+
+// Validate checks that the format is one of the formats supported by the command.
+func Validate(format string, formats ...string) error {
+	if !slices.Contains(formats, format) {
+		return fmt.Errorf("invalid output format %q, must be one of: %s", format, strings.Join(formats, ", "))
+	}
+	return nil
+}
+
+// Encode writes the value in a structured format, JSON or YAML.
+func Encode(w io.Writer, format string, v any) error {
+	switch format {
+	case JSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(v)
+	case YAML:
+		encoder := yaml.NewEncoder(w)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(v); err != nil {
+			return err
+		}
+		return encoder.Close()
+	default:
+		return fmt.Errorf("%s is not a structured output format", format)
+	}
+}
+
+// WriteCSV writes the header, then the rows, in the CSV format.
+func WriteCSV(w io.Writer, header []string, rows [][]string) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+	if err := writer.WriteAll(rows); err != nil {
+		return err
+	}
+	return writer.Error()
+}
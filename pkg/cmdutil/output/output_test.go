@@ -0,0 +1,36 @@
+package output
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidate(t *testing.T) {
+	assert.NoError(t, Validate(JSON, Table, JSON))
+	assert.EqualError(t, Validate(YAML, Table, JSON), `invalid output format "yaml", must be one of: table, json`)
+}
+
+func TestEncode(t *testing.T) {
+	value := []map[string]string{{"name": "predev"}}
+
+	var out bytes.Buffer
+	assert.NoError(t, Encode(&out, JSON, value))
+	assert.Equal(t, "[\n  {\n    \"name\": \"predev\"\n  }\n]\n", out.String())
+
+	out.Reset()
+	assert.NoError(t, Encode(&out, YAML, value))
+	assert.Equal(t, "- name: predev\n", out.String())
+
+	assert.EqualError(t, Encode(&out, Table, value), "table is not a structured output format")
+}
+
+func TestWriteCSV(t *testing.T) {
+	var out bytes.Buffer
+	assert.NoError(t, WriteCSV(&out, []string{"name", "environments"}, [][]string{
+		{"tenant1", "dev,prod"},
+		{"tenant2", ""},
+	}))
+	assert.Equal(t, "name,environments\ntenant1,\"dev,prod\"\ntenant2,\n", out.String())
+}
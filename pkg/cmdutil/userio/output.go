@@ -63,6 +63,15 @@ func (s IOStreams) Error(messages string) {
 	logger.GetFileOnlyLogger().Error(messages)
 }
 
+// Banner prints a message standing out from the rest of the output, regardless of the log level
+func (s IOStreams) Banner(message string) {
+	err := s.MsgE(message, s.styles.banner, s.stderr)
+	if err != nil {
+		panic(err.Error())
+	}
+	logger.GetFileOnlyLogger().Warn(message)
+}
+
 func (s *IOStreams) Wizard(title string, completedTitle string) wizard.Handler {
 	if s.IsInteractive() {
 		model, handler, doneSync := wizard.New()
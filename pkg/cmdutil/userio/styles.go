@@ -17,6 +17,7 @@ type styles struct {
 	help         lipgloss.Style
 	suggestion   lipgloss.Style
 	bold         lipgloss.Style
+	banner       lipgloss.Style
 
 	err  lipgloss.Style
 	info lipgloss.Style
@@ -34,6 +35,7 @@ func newStyles(renderer *lipgloss.Renderer) *styles {
 		help:         renderer.NewStyle().Padding(1, 0, 0, 4),
 		suggestion:   renderer.NewStyle().Faint(true),
 		bold:         renderer.NewStyle().Bold(true),
+		banner:       renderer.NewStyle().Bold(true).Foreground(lipgloss.Color("231")).Background(lipgloss.Color("160")).Padding(0, 2),
 
 		err:  niStyles.WarnMessageStyle,
 		info: niStyles.InfoStyle,
@@ -0,0 +1,70 @@
+package env
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"time"
+
+	"github.com/coreeng/corectl/pkg/cmdutil/configpath"
+)
+
+const auditLogFile = "audit.log"
+
+// AuditEntry records a command executed through the tunnel of an environment.
+type AuditEntry struct {
+	Time        time.Time `json:"time"`
+	User        string    `json:"user"`
+	Environment string    `json:"environment"`
+	Tier        string    `json:"tier"`
+	Command     []string  `json:"command"`
+	ExitCode    int       `json:"exitCode"`
+}
+
+// AuditLogPath is the file the audit entries are appended to, one json object per line.
+func AuditLogPath() string {
+	return filepath.Join(configpath.GetCorectlHomeDir(), auditLogFile)
+}
+
+// recordAudit appends the entry to the audit log.
+func recordAudit(entry AuditEntry) error {
+	path := AuditLogPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("create audit log directory: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("open audit log: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	if err := json.NewEncoder(f).Encode(entry); err != nil {
+		return fmt.Errorf("write audit log: %w", err)
+	}
+	return nil
+}
+
+// auditUser is the name of the user running corectl.
+func auditUser() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return os.Getenv("USER")
+}
+
+// exitCode returns the exit code of a command from the error it returned,
+// -1 when the command could not be run.
+func exitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+	return -1
+}
@@ -0,0 +1,48 @@
+package env
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecordAuditAppendsEntries(t *testing.T) {
+	t.Setenv("CORECTL_HOME", t.TempDir())
+
+	for _, name := range []string{"dev", "prod"} {
+		assert.NoError(t, recordAudit(AuditEntry{
+			Time:        time.Now(),
+			User:        "jane",
+			Environment: name,
+			Command:     []string{"kubectl", "get", "pods"},
+		}))
+	}
+
+	f, err := os.Open(AuditLogPath())
+	assert.NoError(t, err)
+	defer func() { _ = f.Close() }()
+	info, err := f.Stat()
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	var environments []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var entry AuditEntry
+		assert.NoError(t, json.Unmarshal(scanner.Bytes(), &entry))
+		environments = append(environments, entry.Environment)
+	}
+	assert.Equal(t, []string{"dev", "prod"}, environments)
+}
+
+func TestExitCode(t *testing.T) {
+	assert.Equal(t, 0, exitCode(nil))
+	assert.Equal(t, 3, exitCode(exec.Command("sh", "-c", "exit 3").Run()))
+	assert.Equal(t, -1, exitCode(errors.New("executable file not found")))
+}
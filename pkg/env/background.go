@@ -0,0 +1,144 @@
+package env
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/coreeng/corectl/pkg/cmdutil/configpath"
+)
+
+// BackgroundLogFile is the file in the proxies directory of the corectl home receiving the output of background proxies.
+const BackgroundLogFile = "background.log"
+
+var ErrNoHandshake = errors.New("no handshake received from the parent process")
+
+// backgroundHandshake is sent by the parent to the background child over its stdin once the child is started.
+// It tells the child which environments to serve, the child binds their ports itself.
+type backgroundHandshake struct {
+	Environments []string `json:"environments"`
+}
+
+// writeHandshake sends the handshake for the environments to the child.
+func writeHandshake(w io.Writer, names []string) error {
+	return json.NewEncoder(w).Encode(backgroundHandshake{Environments: names})
+}
+
+// readHandshake waits for the handshake from the parent, failing when it isn't received within the timeout.
+func readHandshake(r io.Reader, timeout time.Duration) (backgroundHandshake, error) {
+	type result struct {
+		handshake backgroundHandshake
+		err       error
+	}
+	received := make(chan result, 1)
+	go func() {
+		var handshake backgroundHandshake
+		err := json.NewDecoder(r).Decode(&handshake)
+		received <- result{handshake, err}
+	}()
+
+	select {
+	case res := <-received:
+		if res.err != nil {
+			return backgroundHandshake{}, fmt.Errorf("%w: %w", ErrNoHandshake, res.err)
+		}
+		return res.handshake, nil
+	case <-time.After(timeout):
+		return backgroundHandshake{}, fmt.Errorf("%w within %s", ErrNoHandshake, timeout)
+	}
+}
+
+// backgroundEnvironments returns the environments the background child was asked to serve by its parent.
+var backgroundEnvironments = sync.OnceValues(func() ([]string, error) {
+	handshake, err := readHandshake(os.Stdin, backgroundStartTimeout)
+	return handshake.Environments, err
+})
+
+// servedInBackground reports whether the background child was asked to serve the named environment.
+func servedInBackground(name string) (bool, error) {
+	names, err := backgroundEnvironments()
+	if err != nil {
+		return false, err
+	}
+	return slices.Contains(names, name), nil
+}
+
+// startBackground starts the process serving the proxies of the environments in the background,
+// and waits until it is ready, returning its pid.
+// The output of the child goes to the background log file in the corectl home.
+func startBackground(names []string) (int, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return 0, fmt.Errorf("failed to find the corectl executable: %w", err)
+	}
+	logPath := configpath.GetCorectlProxiesDir(BackgroundLogFile)
+	if err := os.MkdirAll(configpath.GetCorectlProxiesDir(), 0o700); err != nil {
+		return 0, err
+	}
+	logFile, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open the background log: %w", err)
+	}
+	defer func() { _ = logFile.Close() }()
+
+	cmd := exec.Command(executable, os.Args[1:]...)
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	cmd.Env = append(os.Environ(), SetBackgroundEnv())
+	cmd.SysProcAttr = detachedProcAttr()
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return 0, err
+	}
+	if err := cmd.Start(); err != nil {
+		return 0, fmt.Errorf("failed to start background process: %w", err)
+	}
+	exited := make(chan error, 1)
+	go func() {
+		exited <- cmd.Wait()
+	}()
+
+	err = writeHandshake(stdin, names)
+	_ = stdin.Close()
+	if err == nil {
+		// the child opens the tunnels of the environments one after the other
+		err = awaitBackground(names, exited, time.Duration(len(names))*backgroundStartTimeout)
+	}
+	if err != nil {
+		_ = cmd.Process.Kill()
+		return 0, fmt.Errorf("background proxy with pid %d did not start, see %s: %w", cmd.Process.Pid, logPath, err)
+	}
+	return cmd.Process.Pid, nil
+}
+
+// awaitBackground waits until the proxies of all the environments answer on their control sockets,
+// failing early when the background process exits.
+func awaitBackground(names []string, exited <-chan error, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for _, name := range names {
+		for {
+			_, err := QueryProxy(name, ControlStatus)
+			if err == nil {
+				break
+			}
+			if time.Now().After(deadline) {
+				return err
+			}
+			select {
+			case exitErr := <-exited:
+				if exitErr == nil {
+					exitErr = errors.New("exited")
+				}
+				return fmt.Errorf("background process stopped: %w", exitErr)
+			case <-time.After(100 * time.Millisecond):
+			}
+		}
+	}
+	return nil
+}
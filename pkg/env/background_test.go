@@ -0,0 +1,132 @@
+//go:build linux
+
+package env
+
+import (
+	"errors"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// backgroundChildDelayEnvVar makes the test binary run as a background child, which serves the control sockets
+// of the environments handed over once the delay it is set to has passed.
+const backgroundChildDelayEnvVar = "CORECTL_TEST_BACKGROUND_CHILD_DELAY"
+
+func TestMain(m *testing.M) {
+	if delay := os.Getenv(backgroundChildDelayEnvVar); delay != "" && os.Getenv(NoBackgroundEnvVar) != "" {
+		os.Exit(slowBackgroundChild(delay))
+	}
+	os.Exit(m.Run())
+}
+
+// slowBackgroundChild stands in for a background child whose tunnels take the delay to open.
+func slowBackgroundChild(delay string) int {
+	d, err := time.ParseDuration(delay)
+	if err != nil {
+		return 1
+	}
+	names, err := backgroundEnvironments()
+	if err != nil {
+		return 1
+	}
+	time.Sleep(d)
+
+	for _, name := range names {
+		control, err := serveControl(&proxyState{name: name, shutdown: func() {}})
+		if err != nil {
+			return 1
+		}
+		defer control.Close()
+	}
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM)
+	<-signals
+	return 0
+}
+
+func TestHandshakeOverPipe(t *testing.T) {
+	r, w, err := os.Pipe()
+	assert.NoError(t, err)
+	defer func() { _ = r.Close() }()
+
+	assert.NoError(t, writeHandshake(w, []string{"dev", "prod"}))
+	assert.NoError(t, w.Close())
+
+	handshake, err := readHandshake(r, time.Second)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"dev", "prod"}, handshake.Environments)
+}
+
+func TestHandshakeTimesOutWithoutParent(t *testing.T) {
+	r, w, err := os.Pipe()
+	assert.NoError(t, err)
+	defer func() { _ = w.Close() }()
+	defer func() { _ = r.Close() }()
+
+	_, err = readHandshake(r, 50*time.Millisecond)
+	assert.ErrorIs(t, err, ErrNoHandshake)
+}
+
+func TestHandshakeFailsWhenParentClosesThePipe(t *testing.T) {
+	r, w, err := os.Pipe()
+	assert.NoError(t, err)
+	defer func() { _ = r.Close() }()
+	assert.NoError(t, w.Close())
+
+	_, err = readHandshake(r, time.Second)
+	assert.ErrorIs(t, err, ErrNoHandshake)
+}
+
+func TestAwaitBackgroundReadyOnceControlSocketsAnswer(t *testing.T) {
+	name := "await-test"
+	_, cancel, stopped := startTestProxy(t, name, pipeDialer{handle: func(remote net.Conn) { _ = remote.Close() }})
+	defer func() {
+		cancel()
+		<-stopped
+	}()
+
+	assert.NoError(t, awaitBackground([]string{name}, make(chan error), time.Second))
+}
+
+func TestAwaitBackgroundFailsWhenChildExits(t *testing.T) {
+	t.Setenv("CORECTL_HOME", t.TempDir())
+	exited := make(chan error, 1)
+	exited <- errors.New("exit status 1")
+
+	err := awaitBackground([]string{"dev"}, exited, 5*time.Second)
+	assert.ErrorContains(t, err, "background process stopped: exit status 1")
+}
+
+func TestAwaitBackgroundTimesOut(t *testing.T) {
+	t.Setenv("CORECTL_HOME", t.TempDir())
+
+	err := awaitBackground([]string{"dev"}, make(chan error), 200*time.Millisecond)
+	assert.Error(t, err)
+}
+
+func TestStartBackgroundWaitsForASlowChild(t *testing.T) {
+	if testing.Short() {
+		t.Skip("the background child takes seconds to start")
+	}
+	t.Setenv("CORECTL_HOME", t.TempDir())
+	// as long as an ssm session which is slow to start, but within its start timeout
+	t.Setenv(backgroundChildDelayEnvVar, "12s")
+	name := "slow-start"
+
+	pid, err := startBackground([]string{name})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = syscall.Kill(pid, syscall.SIGTERM)
+	})
+
+	status, err := QueryProxy(name, ControlStatus)
+	assert.NoError(t, err)
+	assert.Equal(t, pid, status.Pid)
+}
@@ -0,0 +1,10 @@
+//go:build !windows
+
+package env
+
+import "syscall"
+
+// detachedProcAttr starts the background process in its own session, so it isn't hung up with the terminal.
+func detachedProcAttr() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{Setsid: true}
+}
@@ -0,0 +1,14 @@
+//go:build windows
+
+package env
+
+import "syscall"
+
+// detachedProcess is the DETACHED_PROCESS creation flag, the process doesn't inherit the console.
+const detachedProcess = 0x00000008
+
+// detachedProcAttr starts the background process without a console in its own process group,
+// so it isn't stopped with the console.
+func detachedProcAttr() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{CreationFlags: syscall.CREATE_NEW_PROCESS_GROUP | detachedProcess}
+}
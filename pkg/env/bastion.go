@@ -0,0 +1,61 @@
+package env
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/coreeng/core-platform/pkg/environment"
+)
+
+// Bastion is the host tunnels to an environment go through, running the squid proxy clients connect to.
+type Bastion struct {
+	// Instance is the name of the bastion instance
+	Instance string
+	// Zone is the gcp zone of the bastion instance
+	Zone string
+	// Interface is the gcp network interface tunnels connect to
+	Interface string
+	// Port is the port of the squid proxy
+	Port int
+}
+
+// ResolveBastion returns the bastion derived from the environment definition, with the non-zero fields
+// of the override taking precedence.
+func ResolveBastion(env *environment.Environment, override Bastion) Bastion {
+	bastion := Bastion{
+		Instance:  fmt.Sprintf("%s-bastion", env.Environment),
+		Zone:      DefaultZone,
+		Interface: DefaultInterfaceName,
+		Port:      BastionSquidProxyPort,
+	}
+	if p, ok := env.Platform.(*environment.GCPVendor); ok && p.Region != "" {
+		bastion.Zone = gcpZone(p.Region)
+	}
+	return bastion.With(override)
+}
+
+// With returns the bastion with the non-zero fields of the override applied.
+func (b Bastion) With(override Bastion) Bastion {
+	if override.Instance != "" {
+		b.Instance = override.Instance
+	}
+	if override.Zone != "" {
+		b.Zone = override.Zone
+	}
+	if override.Interface != "" {
+		b.Interface = override.Interface
+	}
+	if override.Port != 0 {
+		b.Port = override.Port
+	}
+	return b
+}
+
+// gcpZone returns the location as a zone, a region being mapped to its first zone.
+func gcpZone(location string) string {
+	// zones are regions suffixed with a letter, e.g. europe-west2-a
+	if strings.Count(location, "-") >= 2 {
+		return location
+	}
+	return location + "-a"
+}
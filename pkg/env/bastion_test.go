@@ -0,0 +1,65 @@
+package env
+
+import (
+	"testing"
+
+	"github.com/coreeng/core-platform/pkg/environment"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveBastion(t *testing.T) {
+	tests := []struct {
+		name     string
+		env      *environment.Environment
+		override Bastion
+		expected Bastion
+	}{
+		{
+			name: "defaults",
+			env: &environment.Environment{
+				Environment: "predev",
+				Platform:    &environment.GCPVendor{ProjectId: "gcp-predev-1234"},
+			},
+			expected: Bastion{Instance: "predev-bastion", Zone: "europe-west2-a", Interface: "nic0", Port: 3128},
+		},
+		{
+			name: "zone derived from the region",
+			env: &environment.Environment{
+				Environment: "us-dev",
+				Platform:    &environment.GCPVendor{ProjectId: "gcp-us-1234", Region: "us-central1"},
+			},
+			expected: Bastion{Instance: "us-dev-bastion", Zone: "us-central1-a", Interface: "nic0", Port: 3128},
+		},
+		{
+			name: "zone from the definition",
+			env: &environment.Environment{
+				Environment: "us-dev",
+				Platform:    &environment.GCPVendor{ProjectId: "gcp-us-1234", Region: "us-central1-c"},
+			},
+			expected: Bastion{Instance: "us-dev-bastion", Zone: "us-central1-c", Interface: "nic0", Port: 3128},
+		},
+		{
+			name: "overrides",
+			env: &environment.Environment{
+				Environment: "us-dev",
+				Platform:    &environment.GCPVendor{ProjectId: "gcp-us-1234", Region: "us-central1"},
+			},
+			override: Bastion{Zone: "us-central1-b", Port: 8080},
+			expected: Bastion{Instance: "us-dev-bastion", Zone: "us-central1-b", Interface: "nic0", Port: 8080},
+		},
+		{
+			name: "aws",
+			env: &environment.Environment{
+				Environment: "production",
+				Platform:    &environment.AWSVendor{AccountId: "5678", Region: "eu-west-2"},
+			},
+			override: Bastion{Instance: "shared-bastion"},
+			expected: Bastion{Instance: "shared-bastion", Zone: "europe-west2-a", Interface: "nic0", Port: 3128},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, ResolveBastion(tt.env, tt.override))
+		})
+	}
+}
@@ -2,11 +2,15 @@ package env
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"strconv"
+	"os"
+	"os/exec"
+	"os/signal"
 	"strings"
+	"syscall"
+	"time"
 
-	"github.com/cedws/iapc/iap"
 	"github.com/coreeng/core-platform/pkg/environment"
 	"github.com/coreeng/corectl/pkg/cmdutil/userio"
 	"github.com/coreeng/corectl/pkg/command"
@@ -14,7 +18,6 @@ import (
 	"github.com/coreeng/corectl/pkg/logger"
 	"github.com/coreeng/corectl/pkg/shell"
 	"go.uber.org/zap"
-	"golang.org/x/oauth2/google"
 )
 
 const BastionSquidProxyPort = 3128
@@ -34,43 +37,77 @@ type EnvConnectOpts struct {
 	SilentExec         command.Commander
 	Exec               command.Commander
 	GcpClient          *gcp.Client
+	Provider           Provider
 	Command            []string
 	SkipTunnel         bool
 	Background         bool
 	Force              bool
+	// Socks5 serves a SOCKS5 proxy instead of forwarding connections to the squid proxy as they are
+	Socks5 bool
+	// BastionOverrides override the bastion derived from the definition of the environment with the same name
+	BastionOverrides map[string]Bastion
+	// ProdConfirmed skips the confirmation asked before connecting to a production environment
+	ProdConfirmed bool
+	// IsolatedKubeconfig writes the cluster credentials to the kubeconfig file of the environment,
+	// leaving the global kubeconfig and its current context untouched
+	IsolatedKubeconfig bool
+	// MetricsPort serves the metrics of the proxies in the Prometheus format on the local port, when set
+	MetricsPort int
+	// PinnedPorts are the ports of the proxies of environments set in the config, by environment name
+	PinnedPorts map[string]int
 }
 
-// Connect establishes a connection with a gke cluster via a bastion host
-func Connect(opts EnvConnectOpts) error {
-	s := opts.Streams
+// Bastion returns the bastion of the environment, with the overrides for the environment applied.
+func (o EnvConnectOpts) Bastion(env *environment.Environment) Bastion {
+	return ResolveBastion(env, o.BastionOverrides[env.Environment])
+}
 
-	if opts.Port == 0 {
-		opts.Port = GenerateConnectPort(opts.Environment.Environment)
+// Kubeconfig returns the kubeconfig file of the environment when it is isolated, or an empty string
+// when the default kubeconfig is used.
+func (o EnvConnectOpts) Kubeconfig() string {
+	if !o.IsolatedKubeconfig {
+		return ""
 	}
-	if IsConnectStartup(opts) {
-		existingPid := ExistingPidForConnection(opts.Environment.Environment)
-		if existingPid != 0 {
-			if !opts.Force {
-				logger.Warn().Msgf("Proxy for %s already running with pid %d", opts.Environment.Environment, existingPid)
-				return nil
-			}
-			if err := KillProcess(opts.Environment.Environment, int32(existingPid), false); err != nil {
-				return fmt.Errorf("[%s] failed to kill process: %w", opts.Environment.Environment, err)
-			}
-		}
+	return KubeconfigPath(o.Environment.Environment)
+}
 
-		logger.Info().Msg("Checking platform is supported")
-		defer logger.Info().Msg("Platform is supported")
+// CommandExitError is returned when the command run against the environment exits with a non-zero code,
+// which corectl then exits with.
+type CommandExitError struct {
+	Code int
+}
 
-		if err := checkPlatformSupported(opts.Environment); err != nil {
-			return err
-		}
+func (e CommandExitError) Error() string {
+	return fmt.Sprintf("command exited with code %d", e.Code)
+}
+
+// SignalExitError is returned when the proxies are stopped by a signal, corectl then exits with 128 plus the
+// signal number as shells do. Err is the error of the shutdown, if any.
+type SignalExitError struct {
+	Signal syscall.Signal
+	Err    error
+}
+
+func (e SignalExitError) Error() string {
+	if e.Err != nil {
+		return e.Err.Error()
 	}
+	return fmt.Sprintf("stopped by %s", e.Signal)
+}
 
-	e := opts.Environment.Platform.(*environment.GCPVendor)
-	// Only run startup if we are in the foreground or in the background and parent process
-	proxyUrl, err := setupConnection(s, opts, opts.SilentExec, opts.Environment, opts.Port)
-	if err != nil {
+func (e SignalExitError) Unwrap() error {
+	return e.Err
+}
+
+// Code is the exit code for the signal.
+func (e SignalExitError) Code() int {
+	return 128 + int(e.Signal)
+}
+
+// Connect establishes a connection with a gke or eks cluster via a bastion host
+func Connect(opts EnvConnectOpts) error {
+	running, err := prepareConnection(&opts)
+	if err != nil || running {
 		return err
 	}
 
@@ -78,133 +115,265 @@ func Connect(opts EnvConnectOpts) error {
 	logger.Debug().Msgf("Commands: %+v", opts.Command)
 	if len(opts.Command) > 0 {
 		commandString := strings.Join(opts.Command, " ")
-		logger.Debug().Msgf("iap tunnel command set to: %s", commandString)
+		logger.Debug().Msgf("tunnel command set to: %s", commandString)
 		execute = func() error {
-			stdout, stderr, err := shell.RunCommand(".", opts.Command[0], opts.Command[1:]...)
-			logger.Debug().With(zap.String("command", commandString)).Msgf("stdout: %s, stderr: %s", stdout, stderr)
-			if strings.Trim(string(stderr), " \t") != "" {
-				s.CurrentHandler.Warn(fmt.Sprintf("stderr: %s", stderr))
+			cmd := exec.Command(opts.Command[0], opts.Command[1:]...)
+			cmd.Stdin = os.Stdin
+			cmd.Stdout = os.Stdout
+			cmd.Stderr = os.Stderr
+			cmd.Env = os.Environ()
+			if kubeconfig := opts.Kubeconfig(); kubeconfig != "" {
+				cmd.Env = append(cmd.Env, KubeconfigEnvVar+"="+kubeconfig)
+			}
+			err := shell.RunAttached(cmd)
+			if auditErr := recordAudit(AuditEntry{
+				Time:        time.Now(),
+				User:        auditUser(),
+				Environment: opts.Environment.Environment,
+				Tier:        string(opts.Environment.Tier),
+				Command:     opts.Command,
+				ExitCode:    exitCode(err),
+			}); auditErr != nil {
+				logger.Error().With(zap.Error(auditErr)).Msgf("failed to record %s in the audit log", commandString)
+			}
+			var exitErr *exec.ExitError
+			if errors.As(err, &exitErr) {
+				return CommandExitError{Code: exitErr.ExitCode()}
 			}
 			return err
 		}
 	}
-	if !opts.SkipTunnel { // solely for testing the rest of Connect - IAPC's target websocket endpoint cannot be configured
-		bastionName := fmt.Sprintf("%s-bastion", opts.Environment.Environment)
-		startIAPTunnel(
-			opts,
-			s,
-			e.ProjectId,
-			DefaultZone,
-			proxyUrl,
-			bastionName,
-			BastionSquidProxyPort,
-			DefaultInterfaceName,
-			true,
-			execute,
-		)
+	if opts.SkipTunnel {
+		// The cluster is reached without the tunnel, so the command runs straight away
+		if execute != nil {
+			return execute()
+		}
+		return nil
 	}
-	return nil
+	return startTunnels(opts, []EnvConnectOpts{opts}, execute)
 }
 
-func startIAPTunnel(
-	opts EnvConnectOpts,
-	streams userio.IOStreams,
-	project string,
-	zone string,
-	bind string,
-	instanceName string,
-	port uint16,
-	interfaceName string,
-	compress bool,
-	execute func() error,
-) {
-	ctx := context.Background()
-
-	tokenSource, err := google.DefaultTokenSource(ctx, defaultTokenScopes...)
-	if err != nil {
-		logger.Fatal().With(zap.Error(err)).Msg("failed to get default token source")
+// ConnectAll establishes connections with the clusters of all the environments, serving their proxies
+// from a single process. Each proxy listens on the port assigned to its environment in the port registry.
+func ConnectAll(opts EnvConnectOpts, environments []*environment.Environment) error {
+	if len(opts.Command) > 0 {
+		return errors.New("a command can only be run against a single environment")
 	}
 
-	stringPort := strconv.FormatUint(uint64(port), 10)
-	dialOpts := []iap.DialOption{
-		iap.WithProject(project),
-		iap.WithInstance(instanceName, zone, interfaceName),
-		iap.WithPort(stringPort),
-		iap.WithTokenSource(&tokenSource),
-	}
-	logger.Debug().With(
-		zap.String("project", project),
-		zap.String("instanceName", instanceName),
-		zap.String("zone", zone),
-		zap.String("interfaceName", interfaceName),
-		zap.String("port", stringPort),
-		zap.String("tokenScopes", strings.Join(defaultTokenScopes, ", "))).
-		Msgf("setting iap options")
-	if compress {
-		dialOpts = append(dialOpts, iap.WithCompression())
+	var connections []EnvConnectOpts
+	for _, env := range environments {
+		// The background child only serves the environments the parent handed over in the handshake
+		if IsConnectChild(opts) {
+			served, err := servedInBackground(env.Environment)
+			if err != nil {
+				return err
+			}
+			if !served {
+				continue
+			}
+		}
+
+		envOpts := opts
+		envOpts.Environment = env
+		envOpts.Port = 0
+		running, err := prepareConnection(&envOpts)
+		if err != nil {
+			return fmt.Errorf("[%s] %w", env.Environment, err)
+		}
+		if !running {
+			connections = append(connections, envOpts)
+		}
 	}
 
-	logger.Debug().Msgf("binding to %s", bind)
-	Listen(streams, opts, ctx, bind, dialOpts, execute)
+	if !opts.SkipTunnel && len(connections) > 0 {
+		return startTunnels(opts, connections, nil)
+	}
+	return nil
 }
 
-func setupConnection(streams userio.IOStreams, opts EnvConnectOpts, c command.Commander, env *environment.Environment, port int) (string, error) {
-	e := env.Platform.(*environment.GCPVendor)
-	// TODO: We need to make proxy URL more dynamic
-	proxyUrl := fmt.Sprintf("localhost:%d", port)
-	if !IsConnectStartup(opts) {
-		return proxyUrl, nil
+// prepareConnection configures the cluster credentials for the environment of the options.
+// It reports whether the environment is already served by a running proxy, in which case nothing is configured.
+func prepareConnection(opts *EnvConnectOpts) (bool, error) {
+	if opts.Port == 0 {
+		// The background child serves the port the parent bound, which is therefore in use
+		port, err := assignPort(*opts, IsConnectStartup(*opts))
+		if err != nil {
+			return false, err
+		}
+		opts.Port = port
 	}
+	if IsConnectStartup(*opts) {
+		if existing, err := QueryProxy(opts.Environment.Environment, ControlStatus); err == nil {
+			if !opts.Force {
+				logger.Warn().Msgf("Proxy for %s already running with pid %d", opts.Environment.Environment, existing.Pid)
+				return true, nil
+			}
+			if err := ShutdownProxy(opts.Environment.Environment); err != nil {
+				return false, fmt.Errorf("[%s] %w", opts.Environment.Environment, err)
+			}
+		}
 
-	logger.Info().Msgf("Retrieving cluster credentials: project=%s zone=%s cluster=%s", e.ProjectId, e.Region, env.Environment)
+		if err := confirmProd(*opts); err != nil {
+			return false, err
+		}
 
-	if err := setCredentials(c, env.Environment, e.ProjectId, e.Region); err != nil {
-		logger.Error().Msg(err.Error())
-		return "", err
+		logger.Info().Msg("Checking platform is supported")
+		defer logger.Info().Msg("Platform is supported")
+
+		if err := checkPlatformSupported(opts.Environment); err != nil {
+			return false, err
+		}
 	}
-	logger.Info().Msgf("Configured cluster credentials: project=%s zone=%s cluster=%s", e.ProjectId, e.Region, env.Environment)
 
-	context := fmt.Sprintf("gke_%s_%s_%s", e.ProjectId, e.Region, env.Environment)
-	logger.Info().Msgf("Setting Kubernetes config context to: %s", context)
+	if opts.Provider == nil {
+		provider, err := NewProvider(opts.Environment, opts.Bastion(opts.Environment), opts.SilentExec, opts.GcpClient)
+		if err != nil {
+			return false, err
+		}
+		opts.Provider = provider
+	}
 
-	if err := setKubeContext(c, context); err != nil {
-		logger.Error().Msg(err.Error())
-		return "", err
+	// Only run startup if we are in the foreground or in the background and parent process
+	return false, setupConnection(*opts)
+}
+
+// startTunnels opens the tunnels for all the connections and serves their proxies until they are shut down,
+// or until the process is interrupted or terminated when there is no command to run, returning the error of the execution if any.
+func startTunnels(
+	opts EnvConnectOpts,
+	connections []EnvConnectOpts,
+	execute func() error,
+) error {
+	ctx := context.Background()
+	// Without a command the proxies run until signalled, an attached command gets the signals meant for it instead
+	if execute == nil {
+		var cancel context.CancelCauseFunc
+		ctx, cancel = context.WithCancelCause(ctx)
+		defer cancel(nil)
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+		defer signal.Stop(signals)
+		go func() {
+			select {
+			case sig := <-signals:
+				signalErr := SignalExitError{Signal: syscall.SIGTERM}
+				if s, ok := sig.(syscall.Signal); ok {
+					signalErr.Signal = s
+				}
+				cancel(signalErr)
+			case <-ctx.Done():
+			}
+		}()
 	}
-	logger.Warn().Msgf("Kubernetes config context set to: %s", context)
 
-	if !opts.SkipTunnel {
-		logger.Info().Msgf("Setting Kubernetes proxy url to: %s", proxyUrl)
-		if err := setKubeProxy(c, context, proxyUrl); err != nil {
-			logger.Error().Msg(err.Error())
-			return "", err
+	providers := make([]Provider, len(connections))
+	for i, c := range connections {
+		providers[i] = c.Provider
+	}
+	if err := shareTokenSource(ctx, providers); err != nil {
+		return fmt.Errorf("failed to open tunnels: %w", err)
+	}
+
+	targets := make([]proxyTarget, len(connections))
+	for i, c := range connections {
+		dialer, err := NewHealthCheckedDialer(ctx, c.Environment.Environment, c.Provider.Tunnel)
+		if err != nil {
+			return fmt.Errorf("failed to open tunnel: %s: %w", c.Provider, err)
 		}
+		defer dialer.Close()
 
-		logger.Info().Msgf("Kubernetes proxy url set to: %s", proxyUrl)
+		targets[i] = proxyTarget{
+			name:    c.Environment.Environment,
+			address: proxyAddress(c.Port),
+			dialer:  dialer,
+			socks5:  c.Socks5,
+			stop:    dialer.Close,
+		}
+		// The background parent only starts the child, health is monitored by the process serving connections
+		if !IsConnectParent(opts) {
+			healthCtx, stopHealthChecks := context.WithCancel(ctx)
+			defer stopHealthChecks()
+			go dialer.Run(healthCtx)
+			targets[i].stop = func() {
+				stopHealthChecks()
+				dialer.Close()
+			}
+		}
 	}
 
-	return proxyUrl, nil
+	err := serveProxies(opts.Streams, opts, ctx, targets, execute)
+	var signalErr SignalExitError
+	if errors.As(context.Cause(ctx), &signalErr) {
+		signalErr.Err = err
+		return signalErr
+	}
+	return err
 }
 
-func setCredentials(c command.Commander, cluster, projectID, region string) error {
-	if _, err := c.Execute("gcloud", command.WithArgs("container", "clusters", "get-credentials", "--project", projectID, "--zone", region, "--dns-endpoint", cluster)); err != nil {
-		return fmt.Errorf("get gcp cluster credentials: %w", err)
+// assignPort returns the port of the proxy of the environment from the port registry,
+// which is locked so that concurrent invocations don't assign the same port.
+func assignPort(opts EnvConnectOpts, checkAvailable bool) (int, error) {
+	unlock, err := lockPortRegistry()
+	if err != nil {
+		return 0, err
 	}
-	return nil
+	defer unlock()
+	registry, err := LoadPortRegistry(opts.PinnedPorts)
+	if err != nil {
+		return 0, err
+	}
+	return registry.Assign(opts.Environment.Environment, checkAvailable)
 }
 
-func setKubeContext(c command.Commander, context string) error {
-	namespace := fmt.Sprintf("--namespace=%s", KubeNamespace)
-	if _, err := c.Execute("kubectl", command.WithArgs("config", "set-context", context, namespace)); err != nil {
-		return fmt.Errorf("set kube context %q: %w", context, err)
+// proxyAddress is the local address the proxy for an environment listens on.
+func proxyAddress(port int) string {
+	// TODO: We need to make proxy URL more dynamic
+	return fmt.Sprintf("localhost:%d", port)
+}
+
+// setupConnection writes the kubeconfig entry for the cluster of the environment, going through the proxy
+// unless the tunnel is skipped.
+func setupConnection(opts EnvConnectOpts) error {
+	if !IsConnectStartup(opts) {
+		return nil
 	}
+
+	logger.Info().Msgf("Retrieving cluster credentials: %s", opts.Provider)
+	credentials, err := opts.Provider.KubeCredentials(context.Background())
+	if err != nil {
+		logger.Error().Msg(err.Error())
+		return err
+	}
+
+	entry := kubeconfigEntry{
+		cluster:     opts.Provider.KubeCluster(),
+		context:     opts.Provider.KubeContext(),
+		namespace:   KubeNamespace,
+		credentials: credentials,
+	}
+	if !opts.SkipTunnel {
+		entry.proxyUrl = proxyScheme(opts.Socks5) + "://" + proxyAddress(opts.Port)
+		logger.Info().Msgf("Setting Kubernetes proxy url to: %s", entry.proxyUrl)
+	}
+	kubeconfig := opts.Kubeconfig()
+	if err := writeKubeconfig(kubeconfigPathOptions(kubeconfig), entry); err != nil {
+		logger.Error().Msg(err.Error())
+		return err
+	}
+	if kubeconfig != "" {
+		logger.Warn().Msgf("Kubernetes config for %s written to %s, use it with: export %s=%s",
+			opts.Environment.Environment, kubeconfig, KubeconfigEnvVar, kubeconfig)
+		return nil
+	}
+	logger.Warn().Msgf("Kubernetes config context set to: %s", entry.context)
+
 	return nil
 }
 
-func setKubeProxy(c command.Commander, context, proxy string) error {
-	url := fmt.Sprintf("clusters.%s.proxy-url", context)
-	if _, err := c.Execute("kubectl", command.WithArgs("config", "set", url, "http://"+proxy)); err != nil {
-		return fmt.Errorf("set kube proxy %q: %w", proxy, err)
+// proxyScheme is the scheme of the proxy url clients should use for the proxy.
+func proxyScheme(socks5 bool) string {
+	if socks5 {
+		return "socks5"
 	}
-	return nil
+	return "http"
 }
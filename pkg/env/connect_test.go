@@ -1,23 +1,34 @@
 package env
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/coreeng/core-platform/pkg/environment"
 	"github.com/coreeng/corectl/pkg/cmdutil/userio"
 	"github.com/coreeng/corectl/pkg/command"
+	"github.com/coreeng/corectl/pkg/gcp"
+	gcptest "github.com/coreeng/corectl/pkg/testutil/gcp"
 	"github.com/stretchr/testify/assert"
+	"k8s.io/client-go/tools/clientcmd"
 )
 
 func TestConnectSuccess(t *testing.T) {
 	var proxy = 1234
+	kubeconfig := filepath.Join(t.TempDir(), "config")
+	t.Setenv("KUBECONFIG", kubeconfig)
 
 	env := &environment.Environment{
 		Environment: "predev",
 		Platform: &environment.GCPVendor{
 			ProjectId: "gcp-predev-1234",
+			Region:    "europe-west2",
 		},
 	}
 	streams := userio.NewIOStreams(
@@ -25,16 +36,27 @@ func TestConnectSuccess(t *testing.T) {
 		os.Stdout,
 		os.Stderr,
 	)
+	clusterSvc, err := gcptest.NewClusterMockClient()
+	assert.NoError(t, err)
+	client, err := gcp.NewClient(clusterSvc)
+	assert.NoError(t, err)
 
-	err := Connect(EnvConnectOpts{
+	err = Connect(EnvConnectOpts{
 		Streams:     streams,
 		Environment: env,
 		Port:        proxy,
 		SkipTunnel:  true,
 		Exec:        mockCommanderSuccess{},
 		SilentExec:  mockCommanderSuccess{},
+		GcpClient:   client,
 	})
 	assert.NoError(t, err)
+
+	config, err := clientcmd.LoadFromFile(kubeconfig)
+	assert.NoError(t, err)
+	assert.Equal(t, "gke_gcp-predev-1234_europe-west2_predev", config.CurrentContext)
+	assert.Equal(t, "https://gke-1234.europe-west2.gke.goog", config.Clusters["gke_gcp-predev-1234_europe-west2_predev"].Server)
+	assert.Empty(t, config.Clusters["gke_gcp-predev-1234_europe-west2_predev"].ProxyURL)
 }
 
 func TestConnectFail(t *testing.T) {
@@ -62,6 +84,251 @@ func TestConnectFail(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestConnectThroughTunnel(t *testing.T) {
+	env := &environment.Environment{
+		Environment: fmt.Sprintf("tunnel-test-%d", os.Getpid()),
+		Platform: &environment.GCPVendor{
+			ProjectId: "gcp-predev-1234",
+		},
+	}
+	t.Setenv("CORECTL_HOME", t.TempDir())
+	kubeconfig := filepath.Join(t.TempDir(), "config")
+	t.Setenv("KUBECONFIG", kubeconfig)
+	streams := userio.NewIOStreams(
+		os.Stdin,
+		os.Stdout,
+		os.Stderr,
+	)
+
+	port := freePort(t)
+	err := Connect(EnvConnectOpts{
+		Streams:     streams,
+		Environment: env,
+		Port:        port,
+		Provider:    &fakeProvider{dialer: TCPDialer{Address: startEchoServer(t)}},
+		Command:     []string{"true"},
+		Exec:        mockCommanderSuccess{},
+		SilentExec:  mockCommanderSuccess{},
+	})
+	assert.NoError(t, err)
+
+	config, err := clientcmd.LoadFromFile(kubeconfig)
+	assert.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf("http://localhost:%d", port), config.Clusters["fake-cluster"].ProxyURL)
+}
+
+func TestConnectWithIsolatedKubeconfig(t *testing.T) {
+	env := &environment.Environment{
+		Environment: fmt.Sprintf("isolated-test-%d", os.Getpid()),
+		Platform: &environment.GCPVendor{
+			ProjectId: "gcp-predev-1234",
+		},
+	}
+	t.Setenv("CORECTL_HOME", t.TempDir())
+	global := filepath.Join(t.TempDir(), "config")
+	t.Setenv("KUBECONFIG", global)
+	streams := userio.NewIOStreams(
+		os.Stdin,
+		os.Stdout,
+		os.Stderr,
+	)
+
+	childKubeconfig := filepath.Join(t.TempDir(), "kubeconfig")
+	err := Connect(EnvConnectOpts{
+		Streams:            streams,
+		Environment:        env,
+		Port:               freePort(t),
+		Provider:           &fakeProvider{dialer: TCPDialer{Address: startEchoServer(t)}},
+		Command:            []string{"sh", "-c", "printf %s \"$KUBECONFIG\" > " + childKubeconfig},
+		IsolatedKubeconfig: true,
+		Exec:               mockCommanderSuccess{},
+		SilentExec:         mockCommanderSuccess{},
+	})
+	assert.NoError(t, err)
+
+	isolated := KubeconfigPath(env.Environment)
+	config, err := clientcmd.LoadFromFile(isolated)
+	assert.NoError(t, err)
+	assert.Equal(t, "fake-context", config.CurrentContext)
+	assert.Equal(t, "https://fake-cluster", config.Clusters["fake-cluster"].Server)
+	assert.NoFileExists(t, global)
+
+	seen, err := os.ReadFile(childKubeconfig)
+	assert.NoError(t, err)
+	assert.Equal(t, isolated, string(seen))
+}
+
+func TestConnectToProdRequiresConfirmation(t *testing.T) {
+	t.Setenv("CORECTL_HOME", t.TempDir())
+	t.Setenv("KUBECONFIG", filepath.Join(t.TempDir(), "config"))
+	env := &environment.Environment{
+		Environment: fmt.Sprintf("prod-test-%d", os.Getpid()),
+		Tier:        environment.ProdEnvironmentTier,
+		Platform: &environment.GCPVendor{
+			ProjectId: "gcp-prod-1234",
+		},
+	}
+	opts := EnvConnectOpts{
+		Streams:     userio.NewIOStreamsWithInteractive(os.Stdin, os.Stdout, os.Stderr, false),
+		Environment: env,
+		Port:        freePort(t),
+		Provider:    &fakeProvider{dialer: TCPDialer{Address: startEchoServer(t)}},
+		SkipTunnel:  true,
+		Exec:        mockCommanderSuccess{},
+		SilentExec:  mockCommanderSuccess{},
+	}
+
+	err := Connect(opts)
+	assert.EqualError(t, err, env.Environment+" is a production environment, pass --i-know-this-is-prod to connect to it non-interactively")
+
+	opts.ProdConfirmed = true
+	assert.NoError(t, Connect(opts))
+}
+
+func TestConnectRecordsCommandInAuditLog(t *testing.T) {
+	t.Setenv("CORECTL_HOME", t.TempDir())
+	t.Setenv("KUBECONFIG", filepath.Join(t.TempDir(), "config"))
+	env := &environment.Environment{
+		Environment: fmt.Sprintf("audit-test-%d", os.Getpid()),
+		Tier:        environment.DevEnvironmentTier,
+		Platform: &environment.GCPVendor{
+			ProjectId: "gcp-dev-1234",
+		},
+	}
+
+	err := Connect(EnvConnectOpts{
+		Streams:     userio.NewIOStreams(os.Stdin, os.Stdout, os.Stderr),
+		Environment: env,
+		Port:        freePort(t),
+		Provider:    &fakeProvider{dialer: TCPDialer{Address: startEchoServer(t)}},
+		Command:     []string{"sh", "-c", "exit 3"},
+		Exec:        mockCommanderSuccess{},
+		SilentExec:  mockCommanderSuccess{},
+	})
+	assert.Equal(t, CommandExitError{Code: 3}, err)
+
+	content, err := os.ReadFile(AuditLogPath())
+	assert.NoError(t, err)
+	var entry AuditEntry
+	assert.NoError(t, json.Unmarshal(content, &entry))
+	assert.Equal(t, env.Environment, entry.Environment)
+	assert.Equal(t, "dev", entry.Tier)
+	assert.Equal(t, []string{"sh", "-c", "exit 3"}, entry.Command)
+	assert.Equal(t, 3, entry.ExitCode)
+	assert.NotEmpty(t, entry.User)
+}
+
+func TestConnectRunsCommandWithoutTunnel(t *testing.T) {
+	t.Setenv("CORECTL_HOME", t.TempDir())
+	t.Setenv("KUBECONFIG", filepath.Join(t.TempDir(), "config"))
+	env := &environment.Environment{
+		Environment: fmt.Sprintf("command-test-%d", os.Getpid()),
+		Platform: &environment.GCPVendor{
+			ProjectId: "gcp-dev-1234",
+		},
+	}
+	opts := EnvConnectOpts{
+		Streams:     userio.NewIOStreams(os.Stdin, os.Stdout, os.Stderr),
+		Environment: env,
+		Port:        freePort(t),
+		Provider:    &fakeProvider{},
+		SkipTunnel:  true,
+		Exec:        mockCommanderSuccess{},
+		SilentExec:  mockCommanderSuccess{},
+	}
+
+	output := filepath.Join(t.TempDir(), "output")
+	opts.Command = []string{"sh", "-c", "echo running > " + output}
+	assert.NoError(t, Connect(opts))
+	content, err := os.ReadFile(output)
+	assert.NoError(t, err)
+	assert.Equal(t, "running\n", string(content))
+
+	opts.Command = []string{"sh", "-c", "exit 42"}
+	assert.Equal(t, CommandExitError{Code: 42}, Connect(opts))
+
+	opts.Command = []string{"corectl-missing-command"}
+	err = Connect(opts)
+	assert.Error(t, err)
+	assert.NotErrorAs(t, err, &CommandExitError{})
+}
+
+func TestConnectAllServesEveryEnvironment(t *testing.T) {
+	t.Setenv("CORECTL_HOME", t.TempDir())
+	t.Setenv("KUBECONFIG", filepath.Join(t.TempDir(), "config"))
+	var environments []*environment.Environment
+	for _, name := range []string{"multi-test-a", "multi-test-b"} {
+		environments = append(environments, &environment.Environment{
+			Environment: fmt.Sprintf("%s-%d", name, os.Getpid()),
+			Platform: &environment.GCPVendor{
+				ProjectId: "gcp-predev-1234",
+			},
+		})
+	}
+	streams := userio.NewIOStreams(
+		os.Stdin,
+		os.Stdout,
+		os.Stderr,
+	)
+
+	stopped := make(chan error)
+	go func() {
+		stopped <- ConnectAll(EnvConnectOpts{
+			Streams:    streams,
+			Provider:   &fakeProvider{dialer: TCPDialer{Address: startEchoServer(t)}},
+			Exec:       mockCommanderSuccess{},
+			SilentExec: mockCommanderSuccess{},
+		}, environments)
+	}()
+
+	for _, env := range environments {
+		assert.NoError(t, waitForControlSocket(env.Environment, 5*time.Second))
+		status, err := QueryProxy(env.Environment, ControlStatus)
+		assert.NoError(t, err)
+		assert.Equal(t, os.Getpid(), status.Pid)
+		assert.Equal(t, fmt.Sprintf("localhost:%d", GenerateConnectPort(env.Environment)), status.Address)
+
+		response, err := echo(status.Address, env.Environment)
+		assert.NoError(t, err)
+		assert.Equal(t, env.Environment, response)
+	}
+
+	// shutting down one environment keeps serving the others
+	assert.NoError(t, ShutdownProxy(environments[0].Environment))
+	_, err := QueryProxy(environments[1].Environment, ControlStatus)
+	assert.NoError(t, err)
+
+	assert.NoError(t, ShutdownProxy(environments[1].Environment))
+	select {
+	case err := <-stopped:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("supervisor did not stop after all proxies were shut down")
+	}
+}
+
+func TestConnectAllRejectsCommand(t *testing.T) {
+	err := ConnectAll(EnvConnectOpts{
+		Command: []string{"true"},
+	}, []*environment.Environment{{Environment: "a"}, {Environment: "b"}})
+	assert.EqualError(t, err, "a command can only be run against a single environment")
+}
+
+// fakeProvider serves the tunnel with the given dialer without any cloud credentials
+type fakeProvider struct {
+	dialer Dialer
+}
+
+func (p *fakeProvider) String() string      { return "fake" }
+func (p *fakeProvider) KubeCluster() string { return "fake-cluster" }
+func (p *fakeProvider) KubeContext() string { return "fake-context" }
+func (p *fakeProvider) KubeCredentials(ctx context.Context) (*KubeCredentials, error) {
+	return &KubeCredentials{Server: "https://fake-cluster"}, nil
+}
+func (p *fakeProvider) Tunnel(ctx context.Context) (Dialer, func(), error) {
+	return p.dialer, func() {}, nil
+}
+
 type mockCommanderSuccess struct {
 }
 
@@ -0,0 +1,291 @@
+package env
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/coreeng/core-platform/pkg/environment"
+	"github.com/coreeng/corectl/pkg/cmdutil/configpath"
+	"github.com/coreeng/corectl/pkg/logger"
+	"go.uber.org/zap"
+)
+
+const (
+	ControlStatus   = "status"
+	ControlStats    = "stats"
+	ControlShutdown = "shutdown"
+)
+
+const controlSocketSuffix = ".sock"
+const controlTimeout = 2 * time.Second
+
+// ControlRequest is sent by clients to the control socket of a running proxy, one JSON document per line.
+type ControlRequest struct {
+	Command string `json:"command"`
+}
+
+// ControlResponse is returned by a running proxy for every ControlRequest.
+// Health is only set for status requests and Stats only for stats requests.
+type ControlResponse struct {
+	Environment string `json:"environment"`
+	Pid         int    `json:"pid"`
+	Address     string `json:"address"`
+	// Scheme is the scheme of the proxy url clients use for proxies, http or socks5
+	Scheme string       `json:"scheme,omitempty"`
+	Target string       `json:"target,omitempty"`
+	Health *ProxyStatus `json:"health,omitempty"`
+	Stats  *ProxyStats  `json:"stats,omitempty"`
+	Error  string       `json:"error,omitempty"`
+}
+
+// ProxyStats counts the connections served by a proxy and the bytes transferred through its tunnel.
+type ProxyStats struct {
+	ActiveConnections int64  `json:"activeConnections"`
+	TotalConnections  int64  `json:"totalConnections"`
+	BytesSent         uint64 `json:"bytesSent"`
+	BytesReceived     uint64 `json:"bytesReceived"`
+}
+
+// controlTarget is the process, or part of a process, controlled through a control socket.
+type controlTarget struct {
+	// name is the environment of the target
+	name    string
+	address string
+	// scheme is the scheme of the proxy url, empty for forwards
+	scheme string
+	// target is the service a forward connects to, empty for proxies
+	target string
+	health func() ProxyStatus
+	// stats is nil when the target doesn't count its connections
+	stats    func() ProxyStats
+	shutdown func()
+}
+
+// controlServer answers requests on a control socket.
+type controlServer struct {
+	path     string
+	listener net.Listener
+	target   controlTarget
+}
+
+func controlSocketPath(name string) string {
+	return configpath.GetCorectlProxiesDir(name + controlSocketSuffix)
+}
+
+// serveControl creates the control socket for the proxy and answers requests on it in the background.
+func serveControl(proxy *proxyState) (*controlServer, error) {
+	return listenControl(controlSocketPath(proxy.name), controlTarget{
+		name:     proxy.name,
+		address:  proxy.address,
+		scheme:   proxyScheme(proxy.socks5),
+		health:   proxy.health,
+		stats:    proxy.stats,
+		shutdown: proxy.shutdown,
+	})
+}
+
+// listenControl creates the control socket at the path and answers requests for the target on it in the background.
+func listenControl(path string, target controlTarget) (*controlServer, error) {
+	// The directory is private to the user, so other users can neither query nor stop the proxy
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("create control socket directory: %w", err)
+	}
+	if _, err := queryControl(path, ControlStatus); err == nil {
+		return nil, fmt.Errorf("%s is already running", target.describe())
+	}
+	// Left behind by a proxy which didn't exit cleanly
+	_ = os.Remove(path)
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("create control socket %s: %w", path, err)
+	}
+	if err := os.Chmod(path, 0600); err != nil {
+		_ = listener.Close()
+		return nil, fmt.Errorf("restrict control socket %s: %w", path, err)
+	}
+
+	server := &controlServer{path: path, listener: listener, target: target}
+	go server.serve()
+	return server, nil
+}
+
+// Close stops answering requests and removes the control socket.
+func (s *controlServer) Close() {
+	_ = s.listener.Close()
+	_ = os.Remove(s.path)
+}
+
+func (s *controlServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				logger.Error().With(zap.Error(err)).Msg("failed to accept control connection")
+			}
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *controlServer) handle(conn net.Conn) {
+	defer func() { _ = conn.Close() }()
+	_ = conn.SetDeadline(time.Now().Add(controlTimeout))
+
+	var request ControlRequest
+	line, err := bufio.NewReader(conn).ReadBytes('\n')
+	if err == nil {
+		err = json.Unmarshal(line, &request)
+	}
+
+	response := ControlResponse{
+		Environment: s.target.name,
+		Pid:         os.Getpid(),
+		Address:     s.target.address,
+		Scheme:      s.target.scheme,
+		Target:      s.target.target,
+	}
+	shutdown := false
+	switch {
+	case err != nil:
+		response.Error = fmt.Sprintf("invalid request: %s", err)
+	case request.Command == ControlStatus:
+		health := s.target.health()
+		response.Health = &health
+	case request.Command == ControlStats && s.target.stats != nil:
+		stats := s.target.stats()
+		response.Stats = &stats
+	case request.Command == ControlShutdown:
+		shutdown = true
+	default:
+		response.Error = fmt.Sprintf("unknown command: %s", request.Command)
+	}
+
+	logger.Debug().Msgf("control request %q answered for %s", request.Command, s.target.describe())
+	if err := json.NewEncoder(conn).Encode(response); err != nil {
+		logger.Error().With(zap.Error(err)).Msg("failed to answer control request")
+	}
+	if shutdown {
+		logger.Warn().Msgf("Shutdown of %s requested", s.target.describe())
+		s.target.shutdown()
+	}
+}
+
+func (t controlTarget) describe() string {
+	if t.target != "" {
+		return fmt.Sprintf("forward to %s in %s", t.target, t.name)
+	}
+	return fmt.Sprintf("proxy for %s", t.name)
+}
+
+// QueryProxy sends the command to the control socket of the proxy for the named environment.
+func QueryProxy(name string, command string) (*ControlResponse, error) {
+	response, err := queryControl(controlSocketPath(name), command)
+	if err != nil && response == nil {
+		return nil, fmt.Errorf("proxy for %s: %w", name, err)
+	}
+	return response, err
+}
+
+// queryControl sends the command to the control socket at the path.
+func queryControl(path string, command string) (*ControlResponse, error) {
+	conn, err := net.DialTimeout("unix", path, controlTimeout)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = conn.Close() }()
+	_ = conn.SetDeadline(time.Now().Add(controlTimeout))
+
+	if err := json.NewEncoder(conn).Encode(ControlRequest{Command: command}); err != nil {
+		return nil, fmt.Errorf("send %s request: %w", command, err)
+	}
+	var response ControlResponse
+	if err := json.NewDecoder(conn).Decode(&response); err != nil {
+		return nil, fmt.Errorf("read %s response: %w", command, err)
+	}
+	if response.Error != "" {
+		return &response, errors.New(response.Error)
+	}
+	return &response, nil
+}
+
+// ShutdownProxy asks the proxy for the named environment to stop and waits until its control socket is gone.
+func ShutdownProxy(name string) error {
+	if err := shutdownControl(controlSocketPath(name)); err != nil {
+		return fmt.Errorf("failed to shut down proxy for %s: %w", name, err)
+	}
+	return nil
+}
+
+// shutdownControl asks the target of the control socket at the path to stop and waits until the socket is gone.
+func shutdownControl(path string) error {
+	if _, err := queryControl(path, ControlShutdown); err != nil {
+		return err
+	}
+	// The socket is only removed once the active connections are drained
+	timeout := drainTimeout + controlTimeout
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	return fmt.Errorf("did not shut down within %s", timeout)
+}
+
+// ProxyInfo describes a running proxy as reported through its control socket.
+type ProxyInfo struct {
+	Pid     int
+	Address string
+	Health  *ProxyStatus
+	Stats   *ProxyStats
+}
+
+// GetActiveProxies queries the control sockets of the running proxies for the given environments.
+// Sockets which no longer answer are left behind by proxies which didn't exit cleanly and are removed.
+func GetActiveProxies(environments []environment.Environment) map[string]ProxyInfo {
+	proxies := make(map[string]ProxyInfo)
+	for _, env := range environments {
+		name := env.Environment
+		path := controlSocketPath(name)
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+
+		status, err := QueryProxy(name, ControlStatus)
+		if err != nil {
+			if isStaleSocket(err) {
+				logger.Error().Msgf("removing stale control socket %s", path)
+				_ = os.Remove(path)
+			} else {
+				logger.Error().Msgf("failed to query proxy for %s: %v", name, err)
+			}
+			continue
+		}
+		stats, err := QueryProxy(name, ControlStats)
+		if err != nil {
+			logger.Error().Msgf("failed to query proxy stats for %s: %v", name, err)
+			stats = &ControlResponse{}
+		}
+		proxies[name] = ProxyInfo{
+			Pid:     status.Pid,
+			Address: status.Address,
+			Health:  status.Health,
+			Stats:   stats.Stats,
+		}
+	}
+	return proxies
+}
+
+func isStaleSocket(err error) bool {
+	return errors.Is(err, syscall.ECONNREFUSED)
+}
@@ -0,0 +1,36 @@
+package env
+
+import (
+	"context"
+	"net"
+
+	"github.com/cedws/iapc/iap"
+)
+
+// Dialer opens connections through a tunnel to the bastion's squid proxy.
+type Dialer interface {
+	Dial(ctx context.Context) (net.Conn, error)
+}
+
+// IAPDialer opens connections through an IAP tunnel.
+type IAPDialer struct {
+	Options []iap.DialOption
+}
+
+func (d IAPDialer) Dial(ctx context.Context) (net.Conn, error) {
+	tun, err := iap.Dial(ctx, d.Options...)
+	if err != nil {
+		return nil, err
+	}
+	return tun, nil
+}
+
+// TCPDialer opens plain TCP connections to a fixed address, e.g. a local port forward or a test server.
+type TCPDialer struct {
+	Address string
+}
+
+func (d TCPDialer) Dial(ctx context.Context) (net.Conn, error) {
+	var dialer net.Dialer
+	return dialer.DialContext(ctx, "tcp", d.Address)
+}
@@ -0,0 +1,274 @@
+package env
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coreeng/corectl/pkg/cmdutil/configpath"
+	"github.com/coreeng/corectl/pkg/command"
+	"github.com/coreeng/corectl/pkg/logger"
+	"go.uber.org/zap"
+)
+
+const forwardsDir = "forwards"
+
+// ForwardTarget is a port of an in-cluster service.
+type ForwardTarget struct {
+	Namespace string
+	Service   string
+	Port      int
+}
+
+func (t ForwardTarget) String() string {
+	return fmt.Sprintf("%s/%s:%d", t.Namespace, t.Service, t.Port)
+}
+
+// ParseForwardTarget parses a target in the form `<namespace>/<service>:<port>`.
+func ParseForwardTarget(s string) (ForwardTarget, error) {
+	invalid := fmt.Errorf("invalid target %q, expected <namespace>/<service>:<port>", s)
+	namespace, rest, ok := strings.Cut(s, "/")
+	if !ok || namespace == "" {
+		return ForwardTarget{}, invalid
+	}
+	service, port, ok := strings.Cut(rest, ":")
+	if !ok || service == "" {
+		return ForwardTarget{}, invalid
+	}
+	portNumber, err := strconv.Atoi(port)
+	if err != nil || portNumber < 1 || portNumber > 65535 {
+		return ForwardTarget{}, invalid
+	}
+	return ForwardTarget{Namespace: namespace, Service: service, Port: portNumber}, nil
+}
+
+// forwardRunner runs a port forward until it fails or the context is cancelled.
+type forwardRunner func(ctx context.Context, kubectlArgs []string, target ForwardTarget, localPort int) error
+
+// forwarder keeps a port forward to an in-cluster service alive, restarting it with backoff when it exits.
+type forwarder struct {
+	name        string
+	kubectlArgs []string
+	target      ForwardTarget
+	localPort   int
+	run         forwardRunner
+
+	mu     sync.RWMutex
+	status ProxyStatus
+}
+
+// ErrNoProxy is returned when forwarding to an environment without a running proxy.
+var ErrNoProxy = errors.New("no proxy running")
+
+// Forward forwards the local port to the service in the cluster of the environment until the context is cancelled.
+// It goes through the proxy of the environment, which has to be running.
+func Forward(ctx context.Context, opts EnvConnectOpts, target ForwardTarget, localPort int) error {
+	name := opts.Environment.Environment
+	// The cluster is only reachable through the tunnel, whose proxy env connect writes the kubeconfig entry for
+	if _, err := QueryProxy(name, ControlStatus); err != nil {
+		return fmt.Errorf("%w for %s, run env connect %s first", ErrNoProxy, name, name)
+	}
+	if opts.Provider == nil {
+		provider, err := NewProvider(opts.Environment, opts.Bastion(opts.Environment), opts.SilentExec, opts.GcpClient)
+		if err != nil {
+			return err
+		}
+		opts.Provider = provider
+	}
+	if localPort == 0 {
+		localPort = target.Port
+	}
+
+	kubectlArgs := []string{"--context", opts.Provider.KubeContext()}
+	if kubeconfig := opts.Kubeconfig(); kubeconfig != "" {
+		kubectlArgs = append(kubectlArgs, "--kubeconfig", kubeconfig)
+	}
+	if err := resolveService(opts.SilentExec, kubectlArgs, target); err != nil {
+		return err
+	}
+
+	f := &forwarder{
+		name:        name,
+		kubectlArgs: kubectlArgs,
+		target:      target,
+		localPort:   localPort,
+		run:         kubectlPortForward,
+	}
+	return f.serve(ctx)
+}
+
+// resolveService checks the service exists and exposes the port.
+// The kubectl args select the cluster of the environment.
+func resolveService(c command.Commander, kubectlArgs []string, target ForwardTarget) error {
+	var out bytes.Buffer
+	if _, err := c.Execute("kubectl", command.WithArgs(append(slices.Clone(kubectlArgs),
+		"--namespace", target.Namespace,
+		"get", "service", target.Service,
+		"--output", "jsonpath={.spec.ports[*].port}",
+	)...), command.WithOverrideStdout(&out)); err != nil {
+		return fmt.Errorf("resolve service %s/%s: %w", target.Namespace, target.Service, err)
+	}
+	ports := strings.Fields(out.String())
+	if !slices.Contains(ports, strconv.Itoa(target.Port)) {
+		return fmt.Errorf("service %s/%s does not expose port %d, available ports: %s",
+			target.Namespace, target.Service, target.Port, strings.Join(ports, ", "))
+	}
+	return nil
+}
+
+// serve keeps the forward running and answers requests on its control socket until the context is cancelled
+// or a shutdown is requested.
+func (f *forwarder) serve(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	control, err := listenControl(forwardSocketPath(f.name, f.target), controlTarget{
+		name:     f.name,
+		address:  fmt.Sprintf("localhost:%d", f.localPort),
+		target:   f.target.String(),
+		health:   f.Status,
+		shutdown: cancel,
+	})
+	if err != nil {
+		return err
+	}
+	defer control.Close()
+
+	logger.Warn().Msgf("Forwarding localhost:%d to %s in %s", f.localPort, f.target, f.name)
+	backoff := ReconnectMinBackoff
+	for {
+		started := time.Now()
+		f.recordStart()
+		err := f.run(ctx, f.kubectlArgs, f.target, f.localPort)
+		if ctx.Err() != nil {
+			logger.Warn().Msgf("Forward to %s in %s stopped", f.target, f.name)
+			return nil
+		}
+		if err == nil {
+			err = fmt.Errorf("port forward exited")
+		}
+		f.recordFailure(err)
+		// A forward which ran for a while failed for a new reason, so it is retried straight away
+		if time.Since(started) > ReconnectMaxBackoff {
+			backoff = ReconnectMinBackoff
+		}
+		logger.Warn().With(zap.Error(err)).Msgf("Forward to %s in %s failed, reconnecting in %s", f.target, f.name, backoff)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, ReconnectMaxBackoff)
+	}
+}
+
+// Status returns a snapshot of the current health state.
+func (f *forwarder) Status() ProxyStatus {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.status
+}
+
+func (f *forwarder) recordStart() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !f.status.LastSuccess.IsZero() {
+		f.status.Reconnects++
+	}
+	f.status.Healthy = true
+	f.status.LastSuccess = time.Now()
+	f.status.LastError = ""
+}
+
+func (f *forwarder) recordFailure(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.status.Healthy = false
+	f.status.LastError = err.Error()
+}
+
+func kubectlPortForward(ctx context.Context, kubectlArgs []string, target ForwardTarget, localPort int) error {
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "kubectl", append(slices.Clone(kubectlArgs),
+		"--namespace", target.Namespace,
+		"port-forward", "service/"+target.Service,
+		fmt.Sprintf("%d:%d", localPort, target.Port),
+		"--address", "localhost",
+	)...)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return fmt.Errorf("%w: %s", err, message)
+		}
+		return err
+	}
+	return nil
+}
+
+func forwardSocketPath(name string, target ForwardTarget) string {
+	return configpath.GetCorectlProxiesDir(forwardsDir, name,
+		fmt.Sprintf("%s.%s.%d%s", target.Namespace, target.Service, target.Port, controlSocketSuffix))
+}
+
+// ForwardInfo describes a running forward as reported through its control socket.
+type ForwardInfo struct {
+	Environment string
+	Target      string
+	Pid         int
+	Address     string
+	Health      *ProxyStatus
+}
+
+// GetActiveForwards queries the control sockets of the running forwards for the named environment.
+// Sockets which no longer answer are left behind by forwards which didn't exit cleanly and are removed.
+func GetActiveForwards(name string) []ForwardInfo {
+	paths, err := filepath.Glob(configpath.GetCorectlProxiesDir(forwardsDir, name, "*"+controlSocketSuffix))
+	if err != nil {
+		return nil
+	}
+	var forwards []ForwardInfo
+	for _, path := range paths {
+		status, err := queryControl(path, ControlStatus)
+		if err != nil {
+			if isStaleSocket(err) {
+				logger.Error().Msgf("removing stale control socket %s", path)
+				_ = os.Remove(path)
+			} else {
+				logger.Error().Msgf("failed to query forward %s: %v", path, err)
+			}
+			continue
+		}
+		forwards = append(forwards, ForwardInfo{
+			Environment: status.Environment,
+			Target:      status.Target,
+			Pid:         status.Pid,
+			Address:     status.Address,
+			Health:      status.Health,
+		})
+	}
+	return forwards
+}
+
+// ShutdownForwards stops all the running forwards for the named environment.
+func ShutdownForwards(name string) error {
+	for _, forward := range GetActiveForwards(name) {
+		target, err := ParseForwardTarget(forward.Target)
+		if err != nil {
+			return err
+		}
+		if err := shutdownControl(forwardSocketPath(name, target)); err != nil {
+			return fmt.Errorf("failed to shut down forward to %s in %s: %w", forward.Target, name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package env
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/coreeng/core-platform/pkg/environment"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseForwardTarget(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected ForwardTarget
+		err      bool
+	}{
+		{input: "db/postgres:5432", expected: ForwardTarget{Namespace: "db", Service: "postgres", Port: 5432}},
+		{input: "postgres:5432", err: true},
+		{input: "db/postgres", err: true},
+		{input: "/postgres:5432", err: true},
+		{input: "db/:5432", err: true},
+		{input: "db/postgres:http", err: true},
+		{input: "db/postgres:70000", err: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			target, err := ParseForwardTarget(tt.input)
+			if tt.err {
+				assert.ErrorContains(t, err, "expected <namespace>/<service>:<port>")
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, target)
+			assert.Equal(t, tt.input, target.String())
+		})
+	}
+}
+
+func TestResolveService(t *testing.T) {
+	target := ForwardTarget{Namespace: "db", Service: "postgres", Port: 5432}
+
+	recorder := &recordingCommander{output: "5432 9187"}
+	kubectlArgs := []string{"--context", "gke_project_region_predev", "--kubeconfig", "/home/user/.config/corectl/kube/predev.yaml"}
+	assert.NoError(t, resolveService(recorder, kubectlArgs, target))
+	assert.Equal(t, []string{"kubectl", "--context", "gke_project_region_predev", "--kubeconfig", "/home/user/.config/corectl/kube/predev.yaml",
+		"--namespace", "db", "get", "service", "postgres", "--output", "jsonpath={.spec.ports[*].port}"}, recorder.calls[0])
+
+	err := resolveService(&recordingCommander{output: "80"}, []string{"--context", "ctx"}, target)
+	assert.EqualError(t, err, "service db/postgres does not expose port 5432, available ports: 80")
+
+	err = resolveService(mockCommanderFail{}, []string{"--context", "ctx"}, target)
+	assert.ErrorContains(t, err, "resolve service db/postgres")
+}
+
+func TestForwarderReconnectsUntilShutdown(t *testing.T) {
+	t.Setenv("CORECTL_HOME", t.TempDir())
+	target := ForwardTarget{Namespace: "db", Service: "postgres", Port: 5432}
+
+	runs := make(chan struct{}, 10)
+	f := &forwarder{
+		name:      "forward-test",
+		target:    target,
+		localPort: 15432,
+		run: func(ctx context.Context, kubectlArgs []string, target ForwardTarget, localPort int) error {
+			runs <- struct{}{}
+			if len(runs) == 1 {
+				return errors.New("lost connection to pod")
+			}
+			<-ctx.Done()
+			return ctx.Err()
+		},
+	}
+
+	stopped := make(chan error)
+	go func() { stopped <- f.serve(context.Background()) }()
+
+	assert.Eventually(t, func() bool {
+		forwards := GetActiveForwards("forward-test")
+		return len(forwards) == 1 && forwards[0].Health.Reconnects == 1 && forwards[0].Health.Healthy
+	}, 5*time.Second, 10*time.Millisecond)
+	forwards := GetActiveForwards("forward-test")
+	assert.Equal(t, ForwardInfo{
+		Environment: "forward-test",
+		Target:      "db/postgres:5432",
+		Pid:         os.Getpid(),
+		Address:     "localhost:15432",
+		Health:      forwards[0].Health,
+	}, forwards[0])
+
+	assert.NoError(t, ShutdownForwards("forward-test"))
+	select {
+	case err := <-stopped:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("forward did not stop after shutdown request")
+	}
+	assert.Empty(t, GetActiveForwards("forward-test"))
+}
+
+func TestForwardFailsWithoutProxy(t *testing.T) {
+	t.Setenv("CORECTL_HOME", t.TempDir())
+	opts := EnvConnectOpts{
+		Environment: &environment.Environment{Environment: "predev"},
+		Provider:    &fakeProvider{},
+	}
+
+	err := Forward(context.Background(), opts, ForwardTarget{Namespace: "ns", Service: "svc", Port: 8080}, 0)
+
+	assert.ErrorIs(t, err, ErrNoProxy)
+	assert.ErrorContains(t, err, "run env connect predev first")
+}
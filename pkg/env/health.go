@@ -0,0 +1,173 @@
+package env
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/coreeng/corectl/pkg/logger"
+	"go.uber.org/zap"
+)
+
+const HealthCheckInterval = 30 * time.Second
+const ReconnectMinBackoff = time.Second
+const ReconnectMaxBackoff = time.Minute
+
+// ProxyStatus is the health state of a running proxy, reported through its control socket.
+type ProxyStatus struct {
+	Healthy     bool      `json:"healthy"`
+	LastSuccess time.Time `json:"lastSuccess"`
+	LastError   string    `json:"lastError,omitempty"`
+	Reconnects  int       `json:"reconnects"`
+}
+
+// TunnelFunc opens a tunnel, see Provider.Tunnel.
+type TunnelFunc func(ctx context.Context) (Dialer, func(), error)
+
+// HealthCheckedDialer is a Dialer which periodically probes the tunnel and re-opens it with backoff when probes fail.
+type HealthCheckedDialer struct {
+	name     string
+	tunnel   TunnelFunc
+	interval time.Duration
+
+	mu      sync.RWMutex
+	dialer  Dialer
+	release func()
+	status  ProxyStatus
+	// closed is set once the dialer is closed, a tunnel re-opened afterwards is released straight away
+	closed bool
+}
+
+// errDialerClosed is returned when the tunnel is re-opened after the dialer is closed.
+var errDialerClosed = errors.New("dialer closed")
+
+// NewHealthCheckedDialer opens the tunnel for the named environment and returns a dialer monitoring it.
+func NewHealthCheckedDialer(ctx context.Context, name string, tunnel TunnelFunc) (*HealthCheckedDialer, error) {
+	dialer, release, err := tunnel(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &HealthCheckedDialer{
+		name:     name,
+		tunnel:   tunnel,
+		interval: HealthCheckInterval,
+		dialer:   dialer,
+		release:  release,
+		status:   ProxyStatus{Healthy: true, LastSuccess: time.Now()},
+	}, nil
+}
+
+func (h *HealthCheckedDialer) Dial(ctx context.Context) (net.Conn, error) {
+	h.mu.RLock()
+	dialer := h.dialer
+	h.mu.RUnlock()
+
+	return dialer.Dial(ctx)
+}
+
+// Status returns a snapshot of the current health state.
+func (h *HealthCheckedDialer) Status() ProxyStatus {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.status
+}
+
+// Run probes the tunnel every interval until the context is cancelled.
+func (h *HealthCheckedDialer) Run(ctx context.Context) {
+	ticker := time.NewTicker(h.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			h.check(ctx)
+		}
+	}
+}
+
+// Close releases the tunnel, closing it again has no effect.
+func (h *HealthCheckedDialer) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closed = true
+	h.release()
+	h.release = func() {}
+}
+
+func (h *HealthCheckedDialer) check(ctx context.Context) {
+	h.mu.RLock()
+	dialer := h.dialer
+	h.mu.RUnlock()
+
+	err := testConn(ctx, dialer)
+	if err == nil {
+		h.recordSuccess()
+		return
+	}
+	logger.Warn().With(zap.Error(err)).Msgf("Health check for %s failed, reconnecting", h.name)
+	h.recordFailure(err)
+
+	backoff := ReconnectMinBackoff
+	for {
+		err := h.reconnect(ctx)
+		if err == nil {
+			logger.Warn().Msgf("Reconnected tunnel for %s", h.name)
+			return
+		}
+		if errors.Is(err, errDialerClosed) {
+			return
+		}
+		logger.Warn().With(zap.Error(err)).Msgf("Reconnecting tunnel for %s failed, retrying in %s", h.name, backoff)
+		h.recordFailure(err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, ReconnectMaxBackoff)
+	}
+}
+
+func (h *HealthCheckedDialer) reconnect(ctx context.Context) error {
+	dialer, release, err := h.tunnel(ctx)
+	if err != nil {
+		return err
+	}
+	if err := testConn(ctx, dialer); err != nil {
+		release()
+		return err
+	}
+
+	h.mu.Lock()
+	if h.closed {
+		h.mu.Unlock()
+		release()
+		return errDialerClosed
+	}
+	h.release()
+	h.dialer, h.release = dialer, release
+	h.status.Reconnects++
+	h.mu.Unlock()
+
+	h.recordSuccess()
+	return nil
+}
+
+func (h *HealthCheckedDialer) recordSuccess() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.status.Healthy = true
+	h.status.LastSuccess = time.Now()
+	h.status.LastError = ""
+}
+
+func (h *HealthCheckedDialer) recordFailure(err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.status.Healthy = false
+	h.status.LastError = err.Error()
+}
@@ -0,0 +1,83 @@
+package env
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// tunnelTo returns a TunnelFunc opening tunnels to the given addresses in turn, or failing once they are exhausted
+func tunnelTo(addresses ...string) (TunnelFunc, *int) {
+	released := 0
+	return func(ctx context.Context) (Dialer, func(), error) {
+		if len(addresses) == 0 {
+			return nil, nil, errors.New("no tunnel available")
+		}
+		address := addresses[0]
+		addresses = addresses[1:]
+		return TCPDialer{Address: address}, func() { released++ }, nil
+	}, &released
+}
+
+func TestHealthCheckedDialerReconnects(t *testing.T) {
+	first, err := net.Listen("tcp", "localhost:0")
+	assert.NoError(t, err)
+	tunnel, released := tunnelTo(first.Addr().String(), startEchoServer(t))
+	dialer, err := NewHealthCheckedDialer(context.Background(), "reconnecting", tunnel)
+	assert.NoError(t, err)
+
+	// the first tunnel goes away
+	assert.NoError(t, first.Close())
+	dialer.check(context.Background())
+
+	status := dialer.Status()
+	assert.True(t, status.Healthy)
+	assert.Equal(t, 1, status.Reconnects)
+	assert.Empty(t, status.LastError)
+	assert.Equal(t, 1, *released)
+	assert.NoError(t, testConn(context.Background(), dialer))
+}
+
+func TestHealthCheckedDialerReportsFailure(t *testing.T) {
+	first, err := net.Listen("tcp", "localhost:0")
+	assert.NoError(t, err)
+	tunnel, _ := tunnelTo(first.Addr().String())
+	dialer, err := NewHealthCheckedDialer(context.Background(), "failing", tunnel)
+	assert.NoError(t, err)
+	assert.NoError(t, first.Close())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	dialer.check(ctx)
+
+	status := dialer.Status()
+	assert.False(t, status.Healthy)
+	assert.Equal(t, 0, status.Reconnects)
+	assert.Equal(t, "no tunnel available", status.LastError)
+}
+
+func TestHealthCheckedDialerReleasesTunnelReopenedAfterClose(t *testing.T) {
+	first, err := net.Listen("tcp", "localhost:0")
+	assert.NoError(t, err)
+	tunnel, released := tunnelTo(first.Addr().String(), startEchoServer(t))
+	var dialer *HealthCheckedDialer
+	// the dialer is closed while the tunnel is being re-opened
+	closingTunnel := func(ctx context.Context) (Dialer, func(), error) {
+		if dialer != nil {
+			dialer.Close()
+		}
+		return tunnel(ctx)
+	}
+	dialer, err = NewHealthCheckedDialer(context.Background(), "closing", closingTunnel)
+	assert.NoError(t, err)
+
+	assert.NoError(t, first.Close())
+	dialer.check(context.Background())
+
+	assert.Equal(t, 2, *released, "both the closed and the re-opened tunnels should be released")
+	assert.Equal(t, 0, dialer.Status().Reconnects)
+}
@@ -0,0 +1,135 @@
+package env
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+
+	"github.com/coreeng/corectl/pkg/cmdutil/configpath"
+	"github.com/coreeng/corectl/pkg/logger"
+	"golang.org/x/oauth2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientauthv1 "k8s.io/client-go/pkg/apis/clientauthentication/v1"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// KubeconfigEnvVar is the variable kubernetes clients read the kubeconfig files from.
+const KubeconfigEnvVar = clientcmd.RecommendedConfigPathEnvVar
+
+// corectlCommand is the command clients run corectl with, resolved through the PATH.
+const corectlCommand = "corectl"
+
+// ExecCredentialCommand is the corectl command printing the credentials for gke clusters, used as kubeconfig exec plugin.
+var ExecCredentialCommand = []string{"env", "kube-credentials"}
+
+// KubeCredentials is what clients need to reach and authenticate to a cluster.
+type KubeCredentials struct {
+	Server                   string
+	CertificateAuthorityData []byte
+	Exec                     *clientcmdapi.ExecConfig
+}
+
+// kubeconfigEntry is the cluster, user and context written to the kubeconfig for an environment.
+type kubeconfigEntry struct {
+	cluster     string
+	context     string
+	namespace   string
+	proxyUrl    string
+	credentials *KubeCredentials
+}
+
+// KubeconfigPath is the isolated kubeconfig file of the named environment.
+func KubeconfigPath(name string) string {
+	return configpath.GetCorectlKubeDir(name + ".yaml")
+}
+
+// kubeconfigPathOptions reads and writes the given kubeconfig file only,
+// or the default kubeconfig files when the path is empty.
+func kubeconfigPathOptions(path string) *clientcmd.PathOptions {
+	pathOptions := clientcmd.NewDefaultPathOptions()
+	if path == "" {
+		return pathOptions
+	}
+	pathOptions.GlobalFile = path
+	pathOptions.EnvVar = ""
+	pathOptions.LoadingRules.Precedence = []string{path}
+	return pathOptions
+}
+
+// writeKubeconfig adds the entry to the kubeconfig, replacing any previous entry with the same names,
+// and makes its context the current one.
+func writeKubeconfig(pathOptions *clientcmd.PathOptions, entry kubeconfigEntry) error {
+	config, err := pathOptions.GetStartingConfig()
+	if err != nil {
+		return fmt.Errorf("read kubeconfig: %w", err)
+	}
+
+	cluster := clientcmdapi.NewCluster()
+	cluster.Server = entry.credentials.Server
+	cluster.CertificateAuthorityData = entry.credentials.CertificateAuthorityData
+	cluster.ProxyURL = entry.proxyUrl
+	config.Clusters[entry.cluster] = cluster
+
+	user := clientcmdapi.NewAuthInfo()
+	user.Exec = entry.credentials.Exec
+	config.AuthInfos[entry.context] = user
+
+	context := clientcmdapi.NewContext()
+	context.Cluster = entry.cluster
+	context.AuthInfo = entry.context
+	context.Namespace = entry.namespace
+	config.Contexts[entry.context] = context
+	config.CurrentContext = entry.context
+
+	if err := clientcmd.ModifyConfig(pathOptions, *config, false); err != nil {
+		return fmt.Errorf("write kubeconfig: %w", err)
+	}
+	return nil
+}
+
+// corectlExecConfig authenticates to clusters through the ExecCredentialCommand of the corectl found on the PATH,
+// so the kubeconfig keeps working when the binary is upgraded or moved.
+func corectlExecConfig() *clientcmdapi.ExecConfig {
+	if _, err := exec.LookPath(corectlCommand); err != nil {
+		logger.Warn().Msgf("%s is not found on the PATH, clients need it to authenticate to the cluster", corectlCommand)
+	}
+	return &clientcmdapi.ExecConfig{
+		APIVersion:      clientauthv1.SchemeGroupVersion.String(),
+		Command:         corectlCommand,
+		Args:            ExecCredentialCommand,
+		InstallHint:     "corectl is required on the PATH to authenticate to the cluster, see https://github.com/coreeng/corectl",
+		InteractiveMode: clientcmdapi.NeverExecInteractiveMode,
+	}
+}
+
+// ExecCredential returns the kubeconfig exec plugin output authenticating with the default google credentials.
+func ExecCredential(ctx context.Context) ([]byte, error) {
+	tokenSource, err := defaultTokenSource(ctx)
+	if err != nil {
+		return nil, err
+	}
+	token, err := tokenSource.Token()
+	if err != nil {
+		return nil, fmt.Errorf("get google access token: %w", err)
+	}
+	return execCredential(token)
+}
+
+func execCredential(token *oauth2.Token) ([]byte, error) {
+	credential := clientauthv1.ExecCredential{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: clientauthv1.SchemeGroupVersion.String(),
+			Kind:       "ExecCredential",
+		},
+		Status: &clientauthv1.ExecCredentialStatus{
+			Token: token.AccessToken,
+		},
+	}
+	if !token.Expiry.IsZero() {
+		expiry := metav1.NewTime(token.Expiry)
+		credential.Status.ExpirationTimestamp = &expiry
+	}
+	return json.Marshal(credential)
+}
@@ -0,0 +1,78 @@
+package env
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/oauth2"
+	clientauthv1 "k8s.io/client-go/pkg/apis/clientauthentication/v1"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func TestWriteKubeconfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config")
+	existing := clientcmdapi.NewConfig()
+	existing.Clusters["other"] = &clientcmdapi.Cluster{Server: "https://other"}
+	existing.Contexts["other"] = &clientcmdapi.Context{Cluster: "other"}
+	existing.CurrentContext = "other"
+	assert.NoError(t, clientcmd.WriteToFile(*existing, path))
+
+	pathOptions := clientcmd.NewDefaultPathOptions()
+	pathOptions.LoadingRules.ExplicitPath = path
+	entry := kubeconfigEntry{
+		cluster:   "gke_gcp-predev-1234_europe-west2_predev",
+		context:   "gke_gcp-predev-1234_europe-west2_predev",
+		namespace: KubeNamespace,
+		proxyUrl:  "http://localhost:1234",
+		credentials: &KubeCredentials{
+			Server:                   "https://10.0.0.2",
+			CertificateAuthorityData: []byte("certificate"),
+			Exec:                     &clientcmdapi.ExecConfig{Command: "corectl", Args: ExecCredentialCommand},
+		},
+	}
+	assert.NoError(t, writeKubeconfig(pathOptions, entry))
+
+	config, err := clientcmd.LoadFromFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, entry.context, config.CurrentContext)
+	assert.Equal(t, "https://other", config.Clusters["other"].Server)
+
+	cluster := config.Clusters[entry.cluster]
+	assert.Equal(t, "https://10.0.0.2", cluster.Server)
+	assert.Equal(t, []byte("certificate"), cluster.CertificateAuthorityData)
+	assert.Equal(t, "http://localhost:1234", cluster.ProxyURL)
+	assert.Equal(t, "corectl", config.AuthInfos[entry.context].Exec.Command)
+	assert.Equal(t, KubeNamespace, config.Contexts[entry.context].Namespace)
+
+	// writing the entry again without a proxy replaces it
+	entry.proxyUrl = ""
+	assert.NoError(t, writeKubeconfig(pathOptions, entry))
+	config, err = clientcmd.LoadFromFile(path)
+	assert.NoError(t, err)
+	assert.Empty(t, config.Clusters[entry.cluster].ProxyURL)
+}
+
+func TestCorectlExecConfigRunsCorectlFromThePath(t *testing.T) {
+	exec := corectlExecConfig()
+
+	assert.Equal(t, "corectl", exec.Command)
+	assert.Equal(t, ExecCredentialCommand, exec.Args)
+	assert.Equal(t, clientauthv1.SchemeGroupVersion.String(), exec.APIVersion)
+}
+
+func TestExecCredential(t *testing.T) {
+	expiry := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	output, err := execCredential(&oauth2.Token{AccessToken: "ya29.token", Expiry: expiry})
+	assert.NoError(t, err)
+
+	var credential clientauthv1.ExecCredential
+	assert.NoError(t, json.Unmarshal(output, &credential))
+	assert.Equal(t, "client.authentication.k8s.io/v1", credential.APIVersion)
+	assert.Equal(t, "ExecCredential", credential.Kind)
+	assert.Equal(t, "ya29.token", credential.Status.Token)
+	assert.True(t, expiry.Equal(credential.Status.ExpirationTimestamp.Time))
+}
@@ -1,6 +1,10 @@
 package env
 
 import (
+	"fmt"
+	"strings"
+	"time"
+
 	"github.com/coreeng/core-platform/pkg/environment"
 	"github.com/coreeng/corectl/pkg/cmdutil/userio"
 	"github.com/jedib0t/go-pretty/v6/table"
@@ -9,33 +13,54 @@ import (
 type TableEnv struct {
 	table     table.Writer
 	showProxy bool
+	// ports are the ports assigned to the proxies of environments, shown when set
+	ports map[string]int
 }
 
 func NewTable(streams userio.IOStreams, showProxy bool) TableEnv {
-	t := table.NewWriter()
+	header := table.Row{"Name", "Tier", "ID", "CloudPlatform"}
 	if showProxy {
-		t.AppendHeader(table.Row{"Name", "Tier", "ID", "CloudPlatform", "Proxy", "Pid"})
-	} else {
-		t.AppendHeader(table.Row{"Name", "Tier", "ID", "CloudPlatform"})
+		header = append(header, "Proxy", "Pid", "Health", "LastSuccess", "Reconnects", "Sent", "Received")
 	}
+	return TableEnv{table: newEnvTable(streams, header), showProxy: showProxy}
+}
+
+// NewTableWithPorts lists environments along with the ports assigned to their proxies.
+func NewTableWithPorts(streams userio.IOStreams, ports map[string]int) TableEnv {
+	header := table.Row{"Name", "Tier", "ID", "CloudPlatform", "Port"}
+	return TableEnv{table: newEnvTable(streams, header), ports: ports}
+}
+
+func newEnvTable(streams userio.IOStreams, header table.Row) table.Writer {
+	t := table.NewWriter()
+	t.AppendHeader(header)
 	t.Style().Options.DrawBorder = false
 	t.Style().Options.SeparateColumns = false
 	t.Style().Options.SeparateFooter = false
 	t.Style().Options.SeparateHeader = false
 	t.Style().Options.SeparateRows = false
 	t.SetOutputMirror(streams.GetOutput())
-
-	return TableEnv{table: t, showProxy: showProxy}
+	return t
 }
 
 func (t TableEnv) AppendRow(name, tier, id, platform, proxy, pid string) {
+	t.appendRow(name, tier, id, platform, proxy, pid, nil)
+}
 
-	if t.showProxy {
-		t.table.AppendRows([]table.Row{{name, tier, id, platform, proxy, pid}})
-	} else {
+func (t TableEnv) appendRow(name, tier, id, platform, proxy, pid string, info *ProxyInfo) {
+	switch {
+	case t.showProxy:
+		row := table.Row{name, tier, id, platform, proxy, pid}
+		t.table.AppendRows([]table.Row{append(row, proxyColumns(info)...)})
+	case t.ports != nil:
+		port := "-"
+		if p, ok := t.ports[name]; ok {
+			port = fmt.Sprintf("%d", p)
+		}
+		t.table.AppendRows([]table.Row{{name, tier, id, platform, port}})
+	default:
 		t.table.AppendRows([]table.Row{{name, tier, id, platform}})
 	}
-
 }
 
 func (t TableEnv) Render() string {
@@ -43,6 +68,19 @@ func (t TableEnv) Render() string {
 }
 
 func (t TableEnv) AppendEnv(env environment.Environment, proxy string, pid string) {
+	t.appendEnv(env, proxy, pid, nil)
+}
+
+// AppendEnvWithProxy appends the environment along with the state reported by its running proxy, if any.
+func (t TableEnv) AppendEnvWithProxy(env environment.Environment, info *ProxyInfo) {
+	if info == nil {
+		t.appendEnv(env, "-", "-", nil)
+		return
+	}
+	t.appendEnv(env, info.Address, fmt.Sprintf("%d", info.Pid), info)
+}
+
+func (t TableEnv) appendEnv(env environment.Environment, proxy string, pid string, info *ProxyInfo) {
 	var (
 		platform string
 		id       string
@@ -57,5 +95,182 @@ func (t TableEnv) AppendEnv(env environment.Environment, proxy string, pid strin
 		platform = "AWS"
 	}
 
-	t.AppendRow(env.Environment, string(env.Tier), id, platform, proxy, pid)
+	t.appendRow(env.Environment, string(env.Tier), id, platform, proxy, pid, info)
+}
+
+func proxyColumns(info *ProxyInfo) table.Row {
+	columns := table.Row{"-", "-", "-", "-", "-"}
+	if info == nil {
+		return columns
+	}
+	if status := info.Health; status != nil {
+		columns[0] = "healthy"
+		if !status.Healthy {
+			columns[0] = "unhealthy"
+		}
+		if !status.LastSuccess.IsZero() {
+			columns[1] = time.Since(status.LastSuccess).Round(time.Second).String() + " ago"
+		}
+		columns[2] = fmt.Sprintf("%d", status.Reconnects)
+	}
+	if stats := info.Stats; stats != nil {
+		columns[3] = formatBytes(stats.BytesSent)
+		columns[4] = formatBytes(stats.BytesReceived)
+	}
+	return columns
+}
+
+func formatBytes(b uint64) string {
+	const unit = 1024
+	if b < unit {
+		return fmt.Sprintf("%d B", b)
+	}
+	div, exp := uint64(unit), 0
+	for n := b / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(b)/float64(div), "KMGTPE"[exp])
+}
+
+type TableForward struct {
+	table table.Writer
+}
+
+func NewForwardTable(streams userio.IOStreams) TableForward {
+	t := table.NewWriter()
+	t.AppendHeader(table.Row{"Name", "Forward", "Local", "Pid", "Health", "LastSuccess", "Reconnects"})
+	t.Style().Options.DrawBorder = false
+	t.Style().Options.SeparateColumns = false
+	t.Style().Options.SeparateFooter = false
+	t.Style().Options.SeparateHeader = false
+	t.Style().Options.SeparateRows = false
+	t.SetOutputMirror(streams.GetOutput())
+
+	return TableForward{table: t}
+}
+
+// AppendForward appends the forward along with the state it reported.
+func (t TableForward) AppendForward(info ForwardInfo) {
+	row := table.Row{info.Environment, info.Target, info.Address, fmt.Sprintf("%d", info.Pid)}
+	health := proxyColumns(&ProxyInfo{Health: info.Health})[:3]
+	t.table.AppendRow(append(row, health...))
+}
+
+func (t TableForward) Render() string {
+	return t.table.Render()
+}
+
+type TableResource struct {
+	table table.Writer
+}
+
+func NewResourceTable(streams userio.IOStreams) TableResource {
+	t := table.NewWriter()
+	t.AppendHeader(table.Row{"Name", "Description", "URL"})
+	t.Style().Options.DrawBorder = false
+	t.Style().Options.SeparateColumns = false
+	t.Style().Options.SeparateFooter = false
+	t.Style().Options.SeparateHeader = false
+	t.Style().Options.SeparateRows = false
+	t.SetOutputMirror(streams.GetOutput())
+
+	return TableResource{table: t}
+}
+
+// AppendResource appends the resource with its URL for the environment.
+// Tenant scoped resources show their URL template when no tenant is given.
+func (t TableResource) AppendResource(r Resource, env *environment.Environment, tenant string) {
+	url, err := r.URLFor(env, tenant)
+	if err != nil {
+		url = r.URL
+	}
+	description := r.Description
+	if r.Tenant {
+		description += " (tenant)"
+	}
+	t.table.AppendRow(table.Row{r.Name, description, url})
+}
+
+func (t TableResource) Render() string {
+	return t.table.Render()
+}
+
+type TableClusterStatus struct {
+	table table.Writer
+}
+
+func NewClusterStatusTable(streams userio.IOStreams) TableClusterStatus {
+	t := table.NewWriter()
+	t.AppendHeader(table.Row{"Name", "Tier", "Status", "Version", "Channel", "NodePools", "Maintenance"})
+	t.Style().Options.DrawBorder = false
+	t.Style().Options.SeparateColumns = false
+	t.Style().Options.SeparateFooter = false
+	t.Style().Options.SeparateHeader = false
+	t.Style().Options.SeparateRows = false
+	t.SetOutputMirror(streams.GetOutput())
+
+	return TableClusterStatus{table: t}
+}
+
+// AppendStatus appends the state of the cluster, with a node pool per line.
+func (t TableClusterStatus) AppendStatus(status ClusterStatus) {
+	if status.Error != "" {
+		t.table.AppendRow(table.Row{status.Environment, status.Tier, "ERROR: " + status.Error, "-", "-", "-", "-"})
+		return
+	}
+	pools := make([]string, len(status.NodePools))
+	for i, pool := range status.NodePools {
+		pools[i] = formatNodePool(pool)
+	}
+	t.table.AppendRow(table.Row{
+		status.Environment,
+		status.Tier,
+		status.Status,
+		status.MasterVersion,
+		orDash(status.ReleaseChannel),
+		orDash(strings.Join(pools, "\n")),
+		orDash(status.MaintenanceWindow),
+	})
+}
+
+func (t TableClusterStatus) Render() string {
+	return t.table.Render()
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+type TablePermission struct {
+	table table.Writer
+}
+
+func NewPermissionTable(streams userio.IOStreams) TablePermission {
+	t := table.NewWriter()
+	t.AppendHeader(table.Row{"Permission", "Granted", "Role", "Reason"})
+	t.Style().Options.DrawBorder = false
+	t.Style().Options.SeparateColumns = false
+	t.Style().Options.SeparateFooter = false
+	t.Style().Options.SeparateHeader = false
+	t.Style().Options.SeparateRows = false
+	t.SetOutputMirror(streams.GetOutput())
+
+	return TablePermission{table: t}
+}
+
+// AppendCheck appends the permission along with whether the caller has it.
+func (t TablePermission) AppendCheck(check PermissionCheck) {
+	granted := "yes"
+	if !check.Granted {
+		granted = "MISSING"
+	}
+	t.table.AppendRow(table.Row{check.Permission, granted, check.Role, check.Reason})
+}
+
+func (t TablePermission) Render() string {
+	return t.table.Render()
 }
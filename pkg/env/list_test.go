@@ -114,6 +114,64 @@ func TestAppendRow(t *testing.T) {
 	}
 }
 
+func TestAppendEnvWithPort(t *testing.T) {
+	streams := userio.NewIOStreams(
+		os.Stdin,
+		os.Stdout,
+		os.Stderr,
+	)
+
+	table := NewTableWithPorts(streams, map[string]int{"predev": 36000})
+	table.AppendEnv(environment.Environment{
+		Environment: "predev",
+		Tier:        environment.PreDevEnvironmentTier,
+		Platform:    &environment.GCPVendor{ProjectId: "gcp-predev-1234"},
+	}, "-", "-")
+	table.AppendEnv(environment.Environment{
+		Environment: "production",
+		Tier:        environment.ProdEnvironmentTier,
+		Platform:    &environment.AWSVendor{AccountId: "aws-production-5678"},
+	}, "-", "-")
+	compareOutput(t, table.Render(), `
+	NAME        TIER     ID                   CLOUDPLATFORM  PORT  
+	 predev      pre-dev  gcp-predev-1234      GCP            36000 
+	 production  prod     aws-production-5678  AWS            -`)
+}
+
+func TestAppendEnvWithProxy(t *testing.T) {
+	env := environment.Environment{
+		Environment: "predev",
+		Tier:        environment.PreDevEnvironmentTier,
+		Platform: &environment.GCPVendor{
+			ProjectId: "gcp-predev-1234",
+		},
+	}
+	streams := userio.NewIOStreams(
+		os.Stdin,
+		os.Stdout,
+		os.Stderr,
+	)
+
+	table := NewTable(streams, true)
+	table.AppendEnvWithProxy(env, &ProxyInfo{
+		Pid:     42,
+		Address: "localhost:1234",
+		Health: &ProxyStatus{
+			Healthy:    false,
+			Reconnects: 3,
+		},
+		Stats: &ProxyStats{
+			BytesSent:     512,
+			BytesReceived: 1536,
+		},
+	})
+	table.AppendEnvWithProxy(env, nil)
+	compareOutput(t, table.Render(), `
+			NAME    TIER     ID               CLOUDPLATFORM  PROXY           PID  HEALTH     LASTSUCCESS  RECONNECTS  SENT   RECEIVED 
+			 predev  pre-dev  gcp-predev-1234  GCP            localhost:1234  42   unhealthy  -            3           512 B  1.5 KiB  
+			 predev  pre-dev  gcp-predev-1234  GCP            -               -    -          -            -           -      -`)
+}
+
 func compareOutput(t *testing.T, out string, expected string) {
 	out = strings.TrimSpace(out)
 	expected = strings.ReplaceAll(expected, "\t", "")
@@ -0,0 +1,121 @@
+package env
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coreeng/corectl/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// dialDurationBuckets are the upper bounds in seconds of the buckets of the tunnel dial latency histogram.
+var dialDurationBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// histogram counts observations in cumulative buckets, as exposed to Prometheus.
+type histogram struct {
+	mu     sync.Mutex
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+func (h *histogram) observe(d time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.counts == nil {
+		h.counts = make([]uint64, len(dialDurationBuckets))
+	}
+	seconds := d.Seconds()
+	for i, bound := range dialDurationBuckets {
+		if seconds <= bound {
+			h.counts[i]++
+		}
+	}
+	h.sum += seconds
+	h.count++
+}
+
+func (h *histogram) snapshot() (counts []uint64, sum float64, count uint64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	counts = make([]uint64, len(dialDurationBuckets))
+	copy(counts, h.counts)
+	return counts, h.sum, h.count
+}
+
+// serveMetrics serves the metrics of the proxies in the Prometheus text format on the address,
+// until the returned function is called.
+func serveMetrics(address string, proxies []*proxyState) (func(), error) {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to bind metrics to %s: %w", address, err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metricsHandler(proxies))
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Error().With(zap.Error(err)).Msg("failed to serve metrics")
+		}
+	}()
+	logger.Warn().Msgf("Metrics available at http://%s/metrics", listener.Addr())
+
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = server.Shutdown(ctx)
+	}, nil
+}
+
+func metricsHandler(proxies []*proxyState) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		writeMetrics(w, proxies)
+	})
+}
+
+// writeMetrics writes the metrics of the proxies, labelled by environment, in the Prometheus text format.
+func writeMetrics(w io.Writer, proxies []*proxyState) {
+	metric := func(name, kind, help string, value func(p *proxyState) float64) {
+		_, _ = fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+		for _, p := range proxies {
+			_, _ = fmt.Fprintf(w, "%s{environment=%s} %v\n", name, labelValue(p.name), value(p))
+		}
+	}
+	metric("corectl_proxy_active_connections", "gauge", "Connections currently served by the proxy.",
+		func(p *proxyState) float64 { return float64(p.active.Load()) })
+	metric("corectl_proxy_connections_total", "counter", "Connections accepted by the proxy.",
+		func(p *proxyState) float64 { return float64(p.total.Load()) })
+	metric("corectl_proxy_dial_failures_total", "counter", "Tunnel connections which failed to open.",
+		func(p *proxyState) float64 { return float64(p.dialFailures.Load()) })
+	metric("corectl_proxy_sent_bytes_total", "counter", "Bytes sent through the tunnel.",
+		func(p *proxyState) float64 { return float64(p.sent.Load()) })
+	metric("corectl_proxy_received_bytes_total", "counter", "Bytes received through the tunnel.",
+		func(p *proxyState) float64 { return float64(p.received.Load()) })
+
+	name := "corectl_proxy_dial_duration_seconds"
+	_, _ = fmt.Fprintf(w, "# HELP %s Time taken to open tunnel connections.\n# TYPE %s histogram\n", name, name)
+	for _, p := range proxies {
+		counts, sum, count := p.dialDuration.snapshot()
+		env := labelValue(p.name)
+		for i, bound := range dialDurationBuckets {
+			_, _ = fmt.Fprintf(w, "%s_bucket{environment=%s,le=\"%v\"} %d\n", name, env, bound, counts[i])
+		}
+		_, _ = fmt.Fprintf(w, "%s_bucket{environment=%s,le=\"+Inf\"} %d\n", name, env, count)
+		_, _ = fmt.Fprintf(w, "%s_sum{environment=%s} %v\n", name, env, sum)
+		_, _ = fmt.Fprintf(w, "%s_count{environment=%s} %d\n", name, env, count)
+	}
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func labelValue(value string) string {
+	return `"` + labelEscaper.Replace(value) + `"`
+}
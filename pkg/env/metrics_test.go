@@ -0,0 +1,102 @@
+package env
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coreeng/core-platform/pkg/environment"
+	"github.com/coreeng/corectl/pkg/cmdutil/userio"
+	"github.com/stretchr/testify/assert"
+)
+
+type failingDialer struct{}
+
+func (failingDialer) Dial(ctx context.Context) (net.Conn, error) {
+	return nil, errors.New("tunnel unavailable")
+}
+
+func TestWriteMetrics(t *testing.T) {
+	predev := &proxyState{name: "predev", dialer: TCPDialer{Address: startEchoServer(t)}}
+	conn, err := predev.Dial(context.Background())
+	assert.NoError(t, err)
+	_ = conn.Close()
+	predev.total.Add(3)
+	predev.active.Add(1)
+	predev.sent.Add(512)
+	predev.received.Add(1024)
+
+	dev := &proxyState{name: "dev", dialer: failingDialer{}}
+	_, err = dev.Dial(context.Background())
+	assert.Error(t, err)
+
+	var out strings.Builder
+	writeMetrics(&out, []*proxyState{predev, dev})
+	metrics := out.String()
+
+	assert.Contains(t, metrics, "# TYPE corectl_proxy_active_connections gauge\n")
+	assert.Contains(t, metrics, `corectl_proxy_active_connections{environment="predev"} 1`+"\n")
+	assert.Contains(t, metrics, `corectl_proxy_connections_total{environment="predev"} 3`+"\n")
+	assert.Contains(t, metrics, `corectl_proxy_sent_bytes_total{environment="predev"} 512`+"\n")
+	assert.Contains(t, metrics, `corectl_proxy_received_bytes_total{environment="predev"} 1024`+"\n")
+	assert.Contains(t, metrics, `corectl_proxy_dial_failures_total{environment="predev"} 0`+"\n")
+	assert.Contains(t, metrics, `corectl_proxy_dial_failures_total{environment="dev"} 1`+"\n")
+	assert.Contains(t, metrics, "# TYPE corectl_proxy_dial_duration_seconds histogram\n")
+	assert.Contains(t, metrics, `corectl_proxy_dial_duration_seconds_bucket{environment="predev",le="10"} 1`+"\n")
+	assert.Contains(t, metrics, `corectl_proxy_dial_duration_seconds_bucket{environment="predev",le="+Inf"} 1`+"\n")
+	assert.Contains(t, metrics, `corectl_proxy_dial_duration_seconds_count{environment="predev"} 1`+"\n")
+	assert.Contains(t, metrics, `corectl_proxy_dial_duration_seconds_count{environment="dev"} 0`+"\n")
+}
+
+func TestHistogramBuckets(t *testing.T) {
+	var h histogram
+	h.observe(75 * time.Millisecond)
+	h.observe(3 * time.Second)
+	h.observe(time.Minute)
+
+	counts, sum, count := h.snapshot()
+	assert.Equal(t, []uint64{0, 1, 1, 1, 1, 1, 2, 2}, counts)
+	assert.InDelta(t, 63.075, sum, 0.0001)
+	assert.Equal(t, uint64(3), count)
+}
+
+func TestListenServesMetrics(t *testing.T) {
+	t.Setenv("CORECTL_HOME", t.TempDir())
+	name := "metrics-test"
+	bind := fmt.Sprintf("localhost:%d", freePort(t))
+	metricsPort := freePort(t)
+	opts := EnvConnectOpts{
+		Environment: &environment.Environment{Environment: name},
+		MetricsPort: metricsPort,
+	}
+	streams := userio.NewIOStreams(os.Stdin, os.Stdout, os.Stderr)
+
+	var metrics string
+	execute := func() error {
+		if _, err := echo(bind, "hello"); err != nil {
+			return err
+		}
+		resp, err := http.Get(fmt.Sprintf("http://localhost:%d/metrics", metricsPort))
+		if err != nil {
+			return err
+		}
+		defer func() { _ = resp.Body.Close() }()
+		body, err := io.ReadAll(resp.Body)
+		metrics = string(body)
+		return err
+	}
+
+	assert.NoError(t, Listen(streams, opts, context.Background(), bind, TCPDialer{Address: startEchoServer(t)}, execute))
+	assert.Contains(t, metrics, `corectl_proxy_connections_total{environment="metrics-test"} 1`+"\n")
+	assert.Contains(t, metrics, `corectl_proxy_dial_duration_seconds_count{environment="metrics-test"} 1`+"\n")
+
+	_, err := net.Dial("tcp", fmt.Sprintf("localhost:%d", metricsPort))
+	assert.Error(t, err, "metrics should no longer be served once the proxy stopped")
+}
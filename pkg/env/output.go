@@ -0,0 +1,94 @@
+package env
+
+import (
+	"time"
+
+	"github.com/coreeng/core-platform/pkg/environment"
+)
+
+// EnvironmentOutput describes an environment for env list and env active with -o json or -o yaml.
+// Other tools read it, so fields are only ever added, never renamed or removed, and are documented in env active --help.
+type EnvironmentOutput struct {
+	Name     string          `json:"name" yaml:"name"`
+	Tier     string          `json:"tier" yaml:"tier"`
+	Cloud    string          `json:"cloud" yaml:"cloud"`
+	Id       string          `json:"id" yaml:"id"`
+	Region   string          `json:"region" yaml:"region"`
+	Domains  DomainsOutput   `json:"domains" yaml:"domains"`
+	Port     int             `json:"port,omitempty" yaml:"port,omitempty"`
+	Proxy    *ProxyOutput    `json:"proxy,omitempty" yaml:"proxy,omitempty"`
+	Forwards []ForwardOutput `json:"forwards,omitempty" yaml:"forwards,omitempty"`
+}
+
+type DomainsOutput struct {
+	Ingress          []string `json:"ingress" yaml:"ingress"`
+	InternalServices string   `json:"internalServices" yaml:"internalServices"`
+}
+
+// ProxyOutput describes a running proxy, its health is absent when the proxy didn't report it.
+type ProxyOutput struct {
+	Address string        `json:"address" yaml:"address"`
+	Pid     int           `json:"pid" yaml:"pid"`
+	Health  *HealthOutput `json:"health,omitempty" yaml:"health,omitempty"`
+}
+
+// ForwardOutput describes a running forward to a service of an environment.
+type ForwardOutput struct {
+	Target  string        `json:"target" yaml:"target"`
+	Address string        `json:"address" yaml:"address"`
+	Pid     int           `json:"pid" yaml:"pid"`
+	Health  *HealthOutput `json:"health,omitempty" yaml:"health,omitempty"`
+}
+
+// HealthOutput is the health of the tunnel of a proxy or forward, lastSuccess is absent until a probe succeeded.
+type HealthOutput struct {
+	Healthy     bool       `json:"healthy" yaml:"healthy"`
+	LastSuccess *time.Time `json:"lastSuccess,omitempty" yaml:"lastSuccess,omitempty"`
+	LastError   string     `json:"lastError,omitempty" yaml:"lastError,omitempty"`
+	Reconnects  int        `json:"reconnects" yaml:"reconnects"`
+}
+
+// NewEnvironmentOutput describes the environment along with its running proxy and forwards, if any.
+func NewEnvironmentOutput(env environment.Environment, proxy *ProxyInfo, forwards []ForwardInfo) EnvironmentOutput {
+	out := EnvironmentOutput{
+		Name: env.Environment,
+		Tier: string(env.Tier),
+		Domains: DomainsOutput{
+			Ingress:          []string{},
+			InternalServices: env.InternalServices.Domain,
+		},
+	}
+	switch p := env.Platform.(type) {
+	case *environment.GCPVendor:
+		out.Cloud, out.Id, out.Region = "gcp", p.ProjectId, p.Region
+	case *environment.AWSVendor:
+		out.Cloud, out.Id, out.Region = "aws", p.AccountId, p.Region
+	}
+	for _, d := range env.IngressDomains {
+		out.Domains.Ingress = append(out.Domains.Ingress, d.Domain)
+	}
+	if proxy != nil {
+		out.Proxy = &ProxyOutput{Address: proxy.Address, Pid: proxy.Pid, Health: newHealthOutput(proxy.Health)}
+	}
+	for _, f := range forwards {
+		out.Forwards = append(out.Forwards, ForwardOutput{
+			Target:  f.Target,
+			Address: f.Address,
+			Pid:     f.Pid,
+			Health:  newHealthOutput(f.Health),
+		})
+	}
+	return out
+}
+
+func newHealthOutput(status *ProxyStatus) *HealthOutput {
+	if status == nil {
+		return nil
+	}
+	health := &HealthOutput{Healthy: status.Healthy, LastError: status.LastError, Reconnects: status.Reconnects}
+	if !status.LastSuccess.IsZero() {
+		lastSuccess := status.LastSuccess
+		health.LastSuccess = &lastSuccess
+	}
+	return health
+}
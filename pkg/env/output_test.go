@@ -0,0 +1,65 @@
+package env
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/coreeng/core-platform/pkg/environment"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+func TestEnvironmentOutputFieldNames(t *testing.T) {
+	env := environment.Environment{
+		Environment:      "predev",
+		Tier:             environment.PreDevEnvironmentTier,
+		Platform:         &environment.GCPVendor{ProjectId: "gcp-predev-1234", Region: "europe-west2"},
+		IngressDomains:   []environment.Domain{{Name: "default", Domain: "predev.example.com"}},
+		InternalServices: environment.Domain{Name: "internal", Domain: "internal.predev.example.com"},
+	}
+	proxy := &ProxyInfo{
+		Pid:     1234,
+		Address: "localhost:36000",
+		Health:  &ProxyStatus{Healthy: true, LastSuccess: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC), Reconnects: 2},
+	}
+	forwards := []ForwardInfo{{Environment: "predev", Target: "monitoring/grafana:80", Pid: 5678, Address: "localhost:8080"}}
+
+	out, err := json.Marshal(NewEnvironmentOutput(env, proxy, forwards))
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"name": "predev",
+		"tier": "pre-dev",
+		"cloud": "gcp",
+		"id": "gcp-predev-1234",
+		"region": "europe-west2",
+		"domains": {"ingress": ["predev.example.com"], "internalServices": "internal.predev.example.com"},
+		"proxy": {
+			"address": "localhost:36000",
+			"pid": 1234,
+			"health": {"healthy": true, "lastSuccess": "2024-01-01T12:00:00Z", "reconnects": 2}
+		},
+		"forwards": [{"target": "monitoring/grafana:80", "address": "localhost:8080", "pid": 5678}]
+	}`, string(out))
+}
+
+func TestEnvironmentOutputWithoutProxy(t *testing.T) {
+	env := environment.Environment{
+		Environment: "production",
+		Tier:        environment.ProdEnvironmentTier,
+		Platform:    &environment.AWSVendor{AccountId: "aws-production-5678", Region: "eu-west-2"},
+	}
+
+	out, err := yaml.Marshal(NewEnvironmentOutput(env, nil, nil))
+	assert.NoError(t, err)
+	assert.YAMLEq(t, `
+name: production
+tier: prod
+cloud: aws
+id: aws-production-5678
+region: eu-west-2
+domains:
+  ingress: []
+  internalServices: ""
+`, string(out))
+}
@@ -0,0 +1,98 @@
+package env
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/coreeng/core-platform/pkg/environment"
+	"github.com/coreeng/corectl/pkg/gcp"
+)
+
+// RequiredPermission is an IAM permission needed on the project of an environment to connect to it.
+type RequiredPermission struct {
+	Permission string
+	// Role is a predefined role granting the permission
+	Role string
+	// Reason is what the permission is needed for
+	Reason string
+}
+
+// GCPRequiredPermissions are the permissions needed on the project of a GCP environment to connect to it.
+var GCPRequiredPermissions = []RequiredPermission{
+	{
+		Permission: "container.clusters.get",
+		Role:       "roles/container.clusterViewer",
+		Reason:     "get the endpoint and credentials of the cluster",
+	},
+	{
+		Permission: "compute.instances.get",
+		Role:       "roles/compute.viewer",
+		Reason:     "find the bastion instance",
+	},
+	{
+		Permission: "iap.tunnelInstances.accessViaIAP",
+		Role:       "roles/iap.tunnelResourceAccessor",
+		Reason:     "open an IAP tunnel to the bastion instance",
+	},
+}
+
+// PermissionCheck is the result of testing whether the caller has a required permission.
+type PermissionCheck struct {
+	RequiredPermission
+	Granted bool
+}
+
+// MissingPermissionsError lists the permissions the caller is missing on the project of an environment.
+type MissingPermissionsError struct {
+	Project string
+	Missing []RequiredPermission
+}
+
+func (e MissingPermissionsError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "missing IAM permissions on project %s:", e.Project)
+	for _, p := range e.Missing {
+		fmt.Fprintf(&b, "\n  %s, granted by %s, to %s", p.Permission, p.Role, p.Reason)
+	}
+	return b.String()
+}
+
+// CheckPermissions tests which of the permissions needed to connect to the environment the caller has.
+// Only GCP environments are checked, there are no checks for the others.
+func CheckPermissions(ctx context.Context, client *gcp.Client, env *environment.Environment) ([]PermissionCheck, error) {
+	p, ok := env.Platform.(*environment.GCPVendor)
+	if !ok {
+		return nil, nil
+	}
+
+	permissions := make([]string, len(GCPRequiredPermissions))
+	for i, required := range GCPRequiredPermissions {
+		permissions[i] = required.Permission
+	}
+	granted, err := client.TestPermissions(ctx, p.ProjectId, permissions)
+	if err != nil {
+		return nil, err
+	}
+
+	checks := make([]PermissionCheck, len(GCPRequiredPermissions))
+	for i, required := range GCPRequiredPermissions {
+		checks[i] = PermissionCheck{RequiredPermission: required, Granted: slices.Contains(granted, required.Permission)}
+	}
+	return checks, nil
+}
+
+// missingPermissions returns an error listing the permissions which weren't granted, if any.
+func missingPermissions(project string, checks []PermissionCheck) error {
+	var missing []RequiredPermission
+	for _, check := range checks {
+		if !check.Granted {
+			missing = append(missing, check.RequiredPermission)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	return MissingPermissionsError{Project: project, Missing: missing}
+}
@@ -0,0 +1,161 @@
+package env
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/coreeng/corectl/pkg/cmdutil/configpath"
+	"github.com/coreeng/corectl/pkg/logger"
+	"github.com/gofrs/flock"
+	"gopkg.in/yaml.v3"
+)
+
+// PortsFile is the file of the corectl home recording the port assigned to the proxy of each environment.
+const PortsFile = "ports.yaml"
+
+var ErrPortConflict = errors.New("port conflict")
+
+// PortRegistry assigns stable ports to the proxies of environments, in the range of the generated ports.
+// Ports pinned in the config take precedence over the ports recorded in the registry.
+type PortRegistry struct {
+	path   string
+	ports  map[string]int
+	pinned map[string]int
+}
+
+func PortRegistryPath() string {
+	return filepath.Join(configpath.GetCorectlHomeDir(), PortsFile)
+}
+
+// lockPortRegistry takes an exclusive lock on the port registry, for a single process at a time to load it,
+// assign ports and save them. The returned function releases the lock.
+func lockPortRegistry() (func(), error) {
+	path := PortRegistryPath() + ".lock"
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("create port registry directory: %w", err)
+	}
+	lock := flock.New(path)
+	if err := lock.Lock(); err != nil {
+		return nil, fmt.Errorf("lock port registry: %w", err)
+	}
+	return func() { _ = lock.Unlock() }, nil
+}
+
+// LoadPortRegistry reads the ports recorded in the corectl home, with the given ports pinned by environment name.
+func LoadPortRegistry(pinned map[string]int) (*PortRegistry, error) {
+	r := &PortRegistry{path: PortRegistryPath(), ports: map[string]int{}, pinned: pinned}
+	content, err := os.ReadFile(r.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return r, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read port registry: %w", err)
+	}
+	if err := yaml.Unmarshal(content, &r.ports); err != nil {
+		return nil, fmt.Errorf("parse port registry %s: %w", r.path, err)
+	}
+	if r.ports == nil {
+		r.ports = map[string]int{}
+	}
+	return r, nil
+}
+
+// Port returns the port of the environment, pinned or recorded, if it has one.
+func (r *PortRegistry) Port(name string) (int, bool) {
+	if port, ok := r.pinned[name]; ok {
+		return port, true
+	}
+	port, ok := r.ports[name]
+	return port, ok
+}
+
+// Ports returns the ports of all the environments which have one, pinned or recorded.
+func (r *PortRegistry) Ports() map[string]int {
+	ports := maps.Clone(r.ports)
+	maps.Copy(ports, r.pinned)
+	return ports
+}
+
+// Assign returns the port of the environment, assigning it a new one when it has none yet.
+// When checkAvailable is set, the port must not be in use by any other process than the proxy of the environment:
+// a recorded port in use is replaced with a new one, while a pinned port in use is an error.
+func (r *PortRegistry) Assign(name string, checkAvailable bool) (int, error) {
+	if port, ok := r.pinned[name]; ok {
+		if other := r.owner(port, name); other != "" {
+			return 0, fmt.Errorf("%w: port %d is pinned for both %s and %s", ErrPortConflict, port, name, other)
+		}
+		if checkAvailable && !portAvailable(name, port) {
+			return 0, fmt.Errorf("%w: port %d pinned for %s is in use by another process", ErrPortConflict, port, name)
+		}
+		return port, nil
+	}
+
+	if port, ok := r.ports[name]; ok && r.owner(port, name) == "" {
+		if !checkAvailable || portAvailable(name, port) {
+			return port, nil
+		}
+		logger.Warn().Msgf("Port %d of %s is in use by another process, assigning a new one", port, name)
+	}
+
+	// Start from the port generated for the environment, so that it stays the same as long as it is free
+	start := GenerateConnectPort(name) - PortConnectMin
+	size := PortConnectMax - PortConnectMin
+	for i := range size {
+		port := PortConnectMin + (start+i)%size
+		if r.owner(port, name) != "" || (checkAvailable && !portAvailable(name, port)) {
+			continue
+		}
+		r.ports[name] = port
+		return port, r.save()
+	}
+	return 0, fmt.Errorf("%w: no free port left between %d and %d", ErrPortConflict, PortConnectMin, PortConnectMax)
+}
+
+// owner returns the other environment the port is pinned or recorded for, if any.
+func (r *PortRegistry) owner(port int, name string) string {
+	for _, ports := range []map[string]int{r.pinned, r.ports} {
+		// by name, for conflicts to be reported consistently
+		for _, other := range slices.Sorted(maps.Keys(ports)) {
+			if ports[other] == port && other != name {
+				return other
+			}
+		}
+	}
+	return ""
+}
+
+func (r *PortRegistry) save() error {
+	content, err := yaml.Marshal(r.ports)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(r.path), 0o755); err != nil {
+		return fmt.Errorf("create port registry directory: %w", err)
+	}
+	// Replace the file at once, other corectl processes may be reading it
+	tmp := r.path + ".tmp"
+	if err := os.WriteFile(tmp, content, 0o644); err != nil {
+		return fmt.Errorf("write port registry: %w", err)
+	}
+	if err := os.Rename(tmp, r.path); err != nil {
+		return fmt.Errorf("write port registry: %w", err)
+	}
+	return nil
+}
+
+// portAvailable reports whether the port can be bound, or is already bound by the proxy of the environment.
+func portAvailable(name string, port int) bool {
+	address := proxyAddress(port)
+	listener, err := net.Listen("tcp", address)
+	if err == nil {
+		_ = listener.Close()
+		return true
+	}
+	status, err := QueryProxy(name, ControlStatus)
+	return err == nil && status.Address == address
+}
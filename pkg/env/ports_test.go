@@ -0,0 +1,129 @@
+package env
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/coreeng/core-platform/pkg/environment"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPortRegistryAssignsStablePorts(t *testing.T) {
+	t.Setenv("CORECTL_HOME", t.TempDir())
+
+	registry, err := LoadPortRegistry(nil)
+	assert.NoError(t, err)
+	port, err := registry.Assign("predev", true)
+	assert.NoError(t, err)
+	assert.Equal(t, GenerateConnectPort("predev"), port)
+
+	// the port is recorded, so it stays the same for later invocations
+	registry, err = LoadPortRegistry(nil)
+	assert.NoError(t, err)
+	recorded, ok := registry.Port("predev")
+	assert.True(t, ok)
+	assert.Equal(t, port, recorded)
+	port, err = registry.Assign("predev", true)
+	assert.NoError(t, err)
+	assert.Equal(t, recorded, port)
+	assert.Equal(t, map[string]int{"predev": port}, registry.Ports())
+}
+
+func TestPortRegistryAvoidsCollisions(t *testing.T) {
+	t.Setenv("CORECTL_HOME", t.TempDir())
+	generated := GenerateConnectPort("dev")
+	writePortRegistry(t, fmt.Sprintf("predev: %d\n", generated))
+
+	registry, err := LoadPortRegistry(nil)
+	assert.NoError(t, err)
+	port, err := registry.Assign("dev", false)
+	assert.NoError(t, err)
+	assert.Equal(t, generated+1, port)
+}
+
+func TestPortRegistryReplacesRecordedPortInUse(t *testing.T) {
+	t.Setenv("CORECTL_HOME", t.TempDir())
+	listener, err := net.Listen("tcp", "localhost:0")
+	assert.NoError(t, err)
+	defer func() { _ = listener.Close() }()
+	used := listener.Addr().(*net.TCPAddr).Port
+	writePortRegistry(t, fmt.Sprintf("predev: %d\n", used))
+
+	registry, err := LoadPortRegistry(nil)
+	assert.NoError(t, err)
+	port, err := registry.Assign("predev", true)
+	assert.NoError(t, err)
+	assert.NotEqual(t, used, port)
+
+	// without checking availability the recorded port is kept, e.g. for the port bound by the background child
+	writePortRegistry(t, fmt.Sprintf("predev: %d\n", used))
+	registry, err = LoadPortRegistry(nil)
+	assert.NoError(t, err)
+	port, err = registry.Assign("predev", false)
+	assert.NoError(t, err)
+	assert.Equal(t, used, port)
+}
+
+func TestPortRegistryPinnedPorts(t *testing.T) {
+	t.Setenv("CORECTL_HOME", t.TempDir())
+	pinned := GenerateConnectPort("dev")
+
+	registry, err := LoadPortRegistry(map[string]int{"predev": pinned})
+	assert.NoError(t, err)
+	port, err := registry.Assign("predev", true)
+	assert.NoError(t, err)
+	assert.Equal(t, pinned, port)
+	// the generated port of dev is pinned for predev
+	port, err = registry.Assign("dev", true)
+	assert.NoError(t, err)
+	assert.NotEqual(t, pinned, port)
+
+	registry, err = LoadPortRegistry(map[string]int{"predev": 36000, "dev": 36000})
+	assert.NoError(t, err)
+	_, err = registry.Assign("predev", false)
+	assert.ErrorIs(t, err, ErrPortConflict)
+	assert.EqualError(t, err, "port conflict: port 36000 is pinned for both predev and dev")
+
+	listener, err := net.Listen("tcp", "localhost:0")
+	assert.NoError(t, err)
+	defer func() { _ = listener.Close() }()
+	used := listener.Addr().(*net.TCPAddr).Port
+	registry, err = LoadPortRegistry(map[string]int{"predev": used})
+	assert.NoError(t, err)
+	_, err = registry.Assign("predev", true)
+	assert.EqualError(t, err, fmt.Sprintf("port conflict: port %d pinned for predev is in use by another process", used))
+}
+
+func writePortRegistry(t *testing.T, content string) {
+	assert.NoError(t, os.WriteFile(PortRegistryPath(), []byte(content), 0o644))
+}
+
+func TestAssignPortWaitsForThePortRegistryLock(t *testing.T) {
+	t.Setenv("CORECTL_HOME", t.TempDir())
+	unlock, err := lockPortRegistry()
+	assert.NoError(t, err)
+
+	assigned := make(chan int, 1)
+	go func() {
+		opts := EnvConnectOpts{Environment: &environment.Environment{Environment: "predev"}}
+		port, err := assignPort(opts, false)
+		assert.NoError(t, err)
+		assigned <- port
+	}()
+	select {
+	case <-assigned:
+		t.Fatal("a port should not be assigned while another process holds the lock")
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	unlock()
+	select {
+	case port := <-assigned:
+		assert.Equal(t, GenerateConnectPort("predev"), port)
+	case <-time.After(5 * time.Second):
+		t.Fatal("a port should be assigned once the lock is released")
+	}
+}
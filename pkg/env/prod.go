@@ -0,0 +1,41 @@
+package env
+
+import (
+	"fmt"
+
+	"github.com/coreeng/core-platform/pkg/environment"
+	"github.com/coreeng/corectl/pkg/cmdutil/userio/confirmation"
+)
+
+// ProdConfirmationFlag skips the confirmation asked before connecting to a production environment.
+const ProdConfirmationFlag = "i-know-this-is-prod"
+
+// IsProd reports whether the environment is a production one, which needs to be confirmed before connecting to it.
+func IsProd(env *environment.Environment) bool {
+	return env.Tier == environment.ProdEnvironmentTier
+}
+
+// confirmProd shows the production banner for production environments and asks the user to confirm connecting,
+// unless it was confirmed up front. Non-interactive sessions have to confirm up front.
+func confirmProd(opts EnvConnectOpts) error {
+	env := opts.Environment
+	if !IsProd(env) {
+		return nil
+	}
+	opts.Streams.Banner(fmt.Sprintf("PRODUCTION: %s", env.Environment))
+	if opts.ProdConfirmed {
+		return nil
+	}
+	if !opts.Streams.IsInteractive() {
+		return fmt.Errorf("%s is a production environment, pass --%s to connect to it non-interactively", env.Environment, ProdConfirmationFlag)
+	}
+
+	confirmed, err := confirmation.GetInput(opts.Streams, fmt.Sprintf("%s is a production environment, are you sure you want to connect?", env.Environment))
+	if err != nil {
+		return fmt.Errorf("could not get confirmation from user: %w", err)
+	}
+	if !confirmed {
+		return fmt.Errorf("connection to %s aborted by user", env.Environment)
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package env
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/coreeng/core-platform/pkg/environment"
+	"github.com/coreeng/corectl/pkg/command"
+	"github.com/coreeng/corectl/pkg/gcp"
+)
+
+// Provider abstracts the cloud specific parts of connecting to an environment.
+type Provider interface {
+	fmt.Stringer
+
+	// KubeCluster returns the name of the kubeconfig cluster entry for the environment.
+	KubeCluster() string
+
+	// KubeContext returns the name of the kubeconfig context for the environment.
+	KubeContext() string
+
+	// KubeCredentials returns the endpoint of the cluster and how clients authenticate to it.
+	KubeCredentials(ctx context.Context) (*KubeCredentials, error)
+
+	// Tunnel prepares a tunnel to the bastion and returns a Dialer opening connections through it,
+	// together with a function releasing the resources held by the tunnel.
+	Tunnel(ctx context.Context) (Dialer, func(), error)
+}
+
+// NewProvider returns the Provider for the cloud platform of the given environment, tunnelling through the bastion.
+// The gcp client is only used for gcp environments.
+func NewProvider(env *environment.Environment, bastion Bastion, c command.Commander, client *gcp.Client) (Provider, error) {
+	switch p := env.Platform.(type) {
+	case *environment.GCPVendor:
+		return &gcpProvider{cluster: env.Environment, vendor: p, bastion: bastion, client: client}, nil
+	case *environment.AWSVendor:
+		return &awsProvider{cluster: env.Environment, vendor: p, bastion: bastion, exec: c}, nil
+	default:
+		return nil, fmt.Errorf("%s %w", strings.ToUpper(string(env.Platform.Type())), ErrCloudPlatformNotSupported)
+	}
+}
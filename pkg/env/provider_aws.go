@@ -0,0 +1,158 @@
+package env
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/coreeng/core-platform/pkg/environment"
+	"github.com/coreeng/corectl/pkg/command"
+	"github.com/coreeng/corectl/pkg/logger"
+	"go.uber.org/zap"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+const ssmPortForwardingDocument = "AWS-StartPortForwardingSession"
+const ssmSessionStartTimeout = 30 * time.Second
+
+// awsProvider connects to EKS clusters through an SSM port forwarding session to the bastion.
+type awsProvider struct {
+	cluster string
+	vendor  *environment.AWSVendor
+	bastion Bastion
+	exec    command.Commander
+}
+
+func (p *awsProvider) String() string {
+	return fmt.Sprintf("account=%s region=%s cluster=%s", p.vendor.AccountId, p.vendor.Region, p.cluster)
+}
+
+func (p *awsProvider) KubeCluster() string {
+	return fmt.Sprintf("arn:aws:eks:%s:%s:cluster/%s", p.vendor.Region, p.vendor.AccountId, p.cluster)
+}
+
+func (p *awsProvider) KubeContext() string {
+	return fmt.Sprintf("eks_%s_%s_%s", p.vendor.AccountId, p.vendor.Region, p.cluster)
+}
+
+// KubeCredentials returns the endpoint of the cluster, clients authenticate with tokens from the aws cli.
+func (p *awsProvider) KubeCredentials(ctx context.Context) (*KubeCredentials, error) {
+	var out bytes.Buffer
+	if _, err := p.exec.Execute("aws", command.WithArgs(
+		"eks", "describe-cluster",
+		"--region", p.vendor.Region,
+		"--name", p.cluster,
+		"--query", "cluster.[endpoint,certificateAuthority.data]",
+		"--output", "text",
+	), command.WithOverrideStdout(&out)); err != nil {
+		return nil, fmt.Errorf("get aws cluster credentials: %w", err)
+	}
+	fields := strings.Fields(out.String())
+	if len(fields) != 2 {
+		return nil, fmt.Errorf("get aws cluster credentials: unexpected cluster description %q", out.String())
+	}
+	ca, err := base64.StdEncoding.DecodeString(fields[1])
+	if err != nil {
+		return nil, fmt.Errorf("decode aws cluster certificate: %w", err)
+	}
+
+	return &KubeCredentials{
+		Server:                   fields[0],
+		CertificateAuthorityData: ca,
+		Exec: &clientcmdapi.ExecConfig{
+			APIVersion:      "client.authentication.k8s.io/v1beta1",
+			Command:         "aws",
+			Args:            []string{"--region", p.vendor.Region, "eks", "get-token", "--cluster-name", p.cluster, "--output", "json"},
+			InteractiveMode: clientcmdapi.IfAvailableExecInteractiveMode,
+		},
+	}, nil
+}
+
+// Tunnel starts an SSM port forwarding session from a free loopback port to the bastion's squid proxy.
+// The session is shared by all connections and is terminated by the returned release function.
+func (p *awsProvider) Tunnel(ctx context.Context) (Dialer, func(), error) {
+	instanceId, err := p.bastionInstanceId()
+	if err != nil {
+		return nil, nil, err
+	}
+	localPort, err := freeLocalPort()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	logger.Debug().With(
+		zap.String("region", p.vendor.Region),
+		zap.String("instanceId", instanceId),
+		zap.Int("port", p.bastion.Port),
+		zap.Int("localPort", localPort)).
+		Msg("starting ssm port forwarding session")
+	session := exec.CommandContext(ctx, "aws", "ssm", "start-session",
+		"--region", p.vendor.Region,
+		"--target", instanceId,
+		"--document-name", ssmPortForwardingDocument,
+		"--parameters", fmt.Sprintf("portNumber=%d,localPortNumber=%d", p.bastion.Port, localPort),
+	)
+	if err := session.Start(); err != nil {
+		return nil, nil, fmt.Errorf("start ssm session to %s: %w", instanceId, err)
+	}
+	release := func() {
+		_ = session.Process.Kill()
+		_ = session.Wait()
+	}
+
+	address := fmt.Sprintf("localhost:%d", localPort)
+	if err := waitForPort(address, ssmSessionStartTimeout); err != nil {
+		release()
+		return nil, nil, fmt.Errorf("ssm session to %s: %w", instanceId, err)
+	}
+
+	return TCPDialer{Address: address}, release, nil
+}
+
+// bastionInstanceId looks up the id of the running bastion instance, which is tagged with the bastion name.
+func (p *awsProvider) bastionInstanceId() (string, error) {
+	var out bytes.Buffer
+	name := p.bastion.Instance
+	if _, err := p.exec.Execute("aws", command.WithArgs(
+		"ec2", "describe-instances",
+		"--region", p.vendor.Region,
+		"--filters", "Name=tag:Name,Values="+name, "Name=instance-state-name,Values=running",
+		"--query", "Reservations[0].Instances[0].InstanceId",
+		"--output", "text",
+	), command.WithOverrideStdout(&out)); err != nil {
+		return "", fmt.Errorf("find bastion instance %q: %w", name, err)
+	}
+	instanceId := strings.TrimSpace(out.String())
+	if instanceId == "" || instanceId == "None" {
+		return "", fmt.Errorf("bastion instance %q not found in %s", name, p.vendor.Region)
+	}
+	return instanceId, nil
+}
+
+func freeLocalPort() (int, error) {
+	l, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		return 0, fmt.Errorf("find free local port: %w", err)
+	}
+	defer func() { _ = l.Close() }()
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
+
+func waitForPort(address string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		conn, err := net.DialTimeout("tcp", address, time.Second)
+		if err == nil {
+			return conn.Close()
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("%s not ready after %s: %w", address, timeout, err)
+		}
+		time.Sleep(250 * time.Millisecond)
+	}
+}
@@ -0,0 +1,123 @@
+package env
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/cedws/iapc/iap"
+	"github.com/coreeng/core-platform/pkg/environment"
+	"github.com/coreeng/corectl/pkg/gcp"
+	"github.com/coreeng/corectl/pkg/logger"
+	"go.uber.org/zap"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+)
+
+// gcpProvider connects to GKE clusters through an IAP tunnel to the bastion.
+type gcpProvider struct {
+	cluster string
+	vendor  *environment.GCPVendor
+	bastion Bastion
+	client  *gcp.Client
+	// tokenSource authenticates the tunnel, the default credentials are used when it is nil
+	tokenSource oauth2.TokenSource
+}
+
+func (p *gcpProvider) String() string {
+	return fmt.Sprintf("project=%s zone=%s cluster=%s", p.vendor.ProjectId, p.vendor.Region, p.cluster)
+}
+
+func (p *gcpProvider) KubeCluster() string {
+	return p.KubeContext()
+}
+
+func (p *gcpProvider) KubeContext() string {
+	return fmt.Sprintf("gke_%s_%s_%s", p.vendor.ProjectId, p.vendor.Region, p.cluster)
+}
+
+// KubeCredentials returns the dns endpoint of the cluster, falling back to its ip endpoint when it has none.
+// Clients authenticate with the default google credentials through corectl.
+func (p *gcpProvider) KubeCredentials(ctx context.Context) (*KubeCredentials, error) {
+	if p.client == nil {
+		return nil, errors.New("get gcp cluster credentials: no gcp client")
+	}
+	cluster, err := p.client.GetCluster(ctx, p.cluster, p.vendor.Region, p.vendor.ProjectId)
+	if err != nil {
+		return nil, fmt.Errorf("get gcp cluster credentials: %w", err)
+	}
+	exec := corectlExecConfig()
+
+	// The dns endpoint is served with a publicly trusted certificate
+	if endpoint := cluster.GetControlPlaneEndpointsConfig().GetDnsEndpointConfig().GetEndpoint(); endpoint != "" {
+		return &KubeCredentials{Server: "https://" + endpoint, Exec: exec}, nil
+	}
+	if cluster.GetEndpoint() == "" {
+		return nil, fmt.Errorf("get gcp cluster credentials: cluster %s has no endpoint", p.cluster)
+	}
+	ca, err := base64.StdEncoding.DecodeString(cluster.GetMasterAuth().GetClusterCaCertificate())
+	if err != nil {
+		return nil, fmt.Errorf("decode gcp cluster certificate: %w", err)
+	}
+	return &KubeCredentials{Server: "https://" + cluster.GetEndpoint(), CertificateAuthorityData: ca, Exec: exec}, nil
+}
+
+func (p *gcpProvider) Tunnel(ctx context.Context) (Dialer, func(), error) {
+	tokenSource := p.tokenSource
+	if tokenSource == nil {
+		var err error
+		if tokenSource, err = defaultTokenSource(ctx); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	stringPort := strconv.Itoa(p.bastion.Port)
+	dialOpts := []iap.DialOption{
+		iap.WithProject(p.vendor.ProjectId),
+		iap.WithInstance(p.bastion.Instance, p.bastion.Zone, p.bastion.Interface),
+		iap.WithPort(stringPort),
+		iap.WithTokenSource(&tokenSource),
+		iap.WithCompression(),
+	}
+	logger.Debug().With(
+		zap.String("project", p.vendor.ProjectId),
+		zap.String("instanceName", p.bastion.Instance),
+		zap.String("zone", p.bastion.Zone),
+		zap.String("interfaceName", p.bastion.Interface),
+		zap.String("port", stringPort),
+		zap.String("tokenScopes", strings.Join(defaultTokenScopes, ", "))).
+		Msgf("setting iap options")
+
+	return IAPDialer{Options: dialOpts}, func() {}, nil
+}
+
+// shareTokenSource makes the tunnels of all the gcp providers authenticate through a single token source,
+// so the token is only refreshed once for all of them.
+func shareTokenSource(ctx context.Context, providers []Provider) error {
+	var tokenSource oauth2.TokenSource
+	for _, provider := range providers {
+		p, ok := provider.(*gcpProvider)
+		if !ok {
+			continue
+		}
+		if tokenSource == nil {
+			var err error
+			if tokenSource, err = defaultTokenSource(ctx); err != nil {
+				return err
+			}
+		}
+		p.tokenSource = tokenSource
+	}
+	return nil
+}
+
+func defaultTokenSource(ctx context.Context) (oauth2.TokenSource, error) {
+	tokenSource, err := google.DefaultTokenSource(ctx, defaultTokenScopes...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get default token source: %w", err)
+	}
+	return tokenSource, nil
+}
@@ -0,0 +1,145 @@
+package env
+
+import (
+	"context"
+	"testing"
+
+	"github.com/coreeng/core-platform/pkg/environment"
+	"github.com/coreeng/corectl/pkg/command"
+	"github.com/coreeng/corectl/pkg/gcp"
+	gcptest "github.com/coreeng/corectl/pkg/testutil/gcp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewProvider(t *testing.T) {
+	tests := []struct {
+		name        string
+		env         *environment.Environment
+		description string
+		kubeCluster string
+		kubeContext string
+	}{
+		{
+			name: "GCP environment",
+			env: &environment.Environment{
+				Environment: "predev",
+				Platform: &environment.GCPVendor{
+					ProjectId: "gcp-predev-1234",
+					Region:    "europe-west2",
+				},
+			},
+			description: "project=gcp-predev-1234 zone=europe-west2 cluster=predev",
+			kubeCluster: "gke_gcp-predev-1234_europe-west2_predev",
+			kubeContext: "gke_gcp-predev-1234_europe-west2_predev",
+		},
+		{
+			name: "AWS environment",
+			env: &environment.Environment{
+				Environment: "production",
+				Platform: &environment.AWSVendor{
+					AccountId: "5678",
+					Region:    "eu-west-2",
+				},
+			},
+			description: "account=5678 region=eu-west-2 cluster=production",
+			kubeCluster: "arn:aws:eks:eu-west-2:5678:cluster/production",
+			kubeContext: "eks_5678_eu-west-2_production",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider, err := NewProvider(tt.env, ResolveBastion(tt.env, Bastion{}), mockCommanderSuccess{}, nil)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.description, provider.String())
+			assert.Equal(t, tt.kubeCluster, provider.KubeCluster())
+			assert.Equal(t, tt.kubeContext, provider.KubeContext())
+		})
+	}
+}
+
+func TestGCPProviderKubeCredentials(t *testing.T) {
+	env := &environment.Environment{
+		Environment: "predev",
+		Platform: &environment.GCPVendor{
+			ProjectId: "gcp-predev-1234",
+			Region:    "europe-west2",
+		},
+	}
+	clusterSvc, err := gcptest.NewClusterMockClient()
+	assert.NoError(t, err)
+	client, err := gcp.NewClient(clusterSvc)
+	assert.NoError(t, err)
+	provider, err := NewProvider(env, ResolveBastion(env, Bastion{}), nil, client)
+	assert.NoError(t, err)
+
+	credentials, err := provider.KubeCredentials(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "https://gke-1234.europe-west2.gke.goog", credentials.Server)
+	assert.Empty(t, credentials.CertificateAuthorityData)
+	assert.Equal(t, ExecCredentialCommand, credentials.Exec.Args)
+
+	provider, err = NewProvider(env, ResolveBastion(env, Bastion{}), nil, nil)
+	assert.NoError(t, err)
+	_, err = provider.KubeCredentials(context.Background())
+	assert.ErrorContains(t, err, "get gcp cluster credentials")
+}
+
+func TestAWSProviderKubeCredentials(t *testing.T) {
+	env := &environment.Environment{
+		Environment: "production",
+		Platform: &environment.AWSVendor{
+			AccountId: "5678",
+			Region:    "eu-west-2",
+		},
+	}
+	recorder := &recordingCommander{output: "https://ABCD.gr7.eu-west-2.eks.amazonaws.com\tY2VydGlmaWNhdGU=\n"}
+	provider, err := NewProvider(env, ResolveBastion(env, Bastion{}), recorder, nil)
+	assert.NoError(t, err)
+
+	credentials, err := provider.KubeCredentials(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"aws", "eks", "describe-cluster", "--region", "eu-west-2", "--name", "production",
+		"--query", "cluster.[endpoint,certificateAuthority.data]", "--output", "text"}, recorder.calls[0])
+	assert.Equal(t, "https://ABCD.gr7.eu-west-2.eks.amazonaws.com", credentials.Server)
+	assert.Equal(t, []byte("certificate"), credentials.CertificateAuthorityData)
+	assert.Equal(t, "aws", credentials.Exec.Command)
+	assert.Equal(t, []string{"--region", "eu-west-2", "eks", "get-token", "--cluster-name", "production", "--output", "json"}, credentials.Exec.Args)
+
+	provider, err = NewProvider(env, ResolveBastion(env, Bastion{}), mockCommanderFail{}, nil)
+	assert.NoError(t, err)
+	_, err = provider.KubeCredentials(context.Background())
+	assert.ErrorContains(t, err, "get aws cluster credentials")
+}
+
+func TestAWSProviderBastionInstanceId(t *testing.T) {
+	provider := &awsProvider{
+		cluster: "production",
+		vendor:  &environment.AWSVendor{AccountId: "5678", Region: "eu-west-2"},
+		bastion: Bastion{Instance: "production-bastion"},
+	}
+
+	provider.exec = &recordingCommander{output: "i-0123456789abcdef0\n"}
+	instanceId, err := provider.bastionInstanceId()
+	assert.NoError(t, err)
+	assert.Equal(t, "i-0123456789abcdef0", instanceId)
+
+	provider.exec = &recordingCommander{output: "None\n"}
+	_, err = provider.bastionInstanceId()
+	assert.ErrorContains(t, err, `bastion instance "production-bastion" not found in eu-west-2`)
+}
+
+// recordingCommander records the executed commands and writes output to the overridden stdout
+type recordingCommander struct {
+	calls  [][]string
+	output string
+}
+
+func (r *recordingCommander) Execute(c string, opts ...command.Option) ([]byte, error) {
+	options := command.ApplyOptions(opts)
+	r.calls = append(r.calls, append([]string{c}, options.Args...))
+	if options.Stdout != nil {
+		_, _ = options.Stdout.Write([]byte(r.output))
+	}
+	return nil, nil
+}
@@ -2,143 +2,424 @@ package env
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"maps"
 	"net"
-	"os"
-	"os/exec"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
 
-	"github.com/cedws/iapc/iap"
 	"github.com/coreeng/corectl/pkg/cmdutil/userio"
 	"github.com/coreeng/corectl/pkg/logger"
 	"go.uber.org/zap"
 )
 
-// Listen starts a proxy server that listens on the given address and port.
-func Listen(streams userio.IOStreams, opts EnvConnectOpts, ctx context.Context, listen string, dialOpts []iap.DialOption, execute func() error) {
+// backgroundStartTimeout is how long the background child has to serve the proxy of an environment,
+// which covers opening its tunnel, the slowest being the start of an ssm session.
+const backgroundStartTimeout = ssmSessionStartTimeout + 15*time.Second
 
-	var listener net.Listener
-	var err error
+// drainTimeout is how long a proxy being shut down waits for its active connections to finish before closing them.
+var drainTimeout = 10 * time.Second
 
-	if IsConnectStartup(opts) { // Common code for foreground and background
-		logger.Info().Msg("Testing IAP connection")
-		if err := testConn(ctx, dialOpts); err != nil {
-			err = fmt.Errorf("failed to test connection: %w", err)
-			logger.Fatal().Msg(err.Error())
-		}
-		logger.Info().Msg("IAP connection succeeded")
+var ErrDrainTimeout = errors.New("connections still active after the drain timeout")
 
-		logger.Info().Msgf("Binding to %s", listen)
-		listener, err = net.Listen("tcp", listen)
-		if err != nil {
-			logger.Fatal().With(zap.Error(err)).Msgf("failed to bind to %s", listen)
-			return
-		}
+// proxyState is the state of a running proxy which is exposed through its control socket.
+type proxyState struct {
+	name     string
+	address  string
+	dialer   Dialer
+	socks5   bool
+	shutdown func()
+
+	active       atomic.Int64
+	total        atomic.Int64
+	sent         atomic.Uint64
+	received     atomic.Uint64
+	dialFailures atomic.Int64
+	dialDuration histogram
+
+	// handlers tracks the connections being served, conns the client and tunnel connections they use
+	handlers sync.WaitGroup
+	connsMu  sync.Mutex
+	conns    map[net.Conn]struct{}
+}
+
+// healthReporter is implemented by dialers which monitor the health of their tunnel.
+type healthReporter interface {
+	Status() ProxyStatus
+}
+
+func (p *proxyState) health() ProxyStatus {
+	if reporter, ok := p.dialer.(healthReporter); ok {
+		return reporter.Status()
+	}
+	return ProxyStatus{Healthy: true}
+}
+
+func (p *proxyState) stats() ProxyStats {
+	return ProxyStats{
+		ActiveConnections: p.active.Load(),
+		TotalConnections:  p.total.Load(),
+		BytesSent:         p.sent.Load(),
+		BytesReceived:     p.received.Load(),
+	}
+}
+
+// Dial opens a tunnel connection counting the bytes transferred through it, and the time taken to open it.
+func (p *proxyState) Dial(ctx context.Context) (net.Conn, error) {
+	start := time.Now()
+	conn, err := p.dialer.Dial(ctx)
+	if err != nil {
+		p.dialFailures.Add(1)
+		return nil, err
+	}
+	p.dialDuration.observe(time.Since(start))
+	counting := &countingConn{Conn: conn, sent: &p.sent, received: &p.received}
+	counting.untrack = p.track(counting)
+	return counting, nil
+}
+
+// track records the connection until the returned function is called, so that it can be closed when draining times out.
+func (p *proxyState) track(conn net.Conn) func() {
+	p.connsMu.Lock()
+	defer p.connsMu.Unlock()
+	if p.conns == nil {
+		p.conns = make(map[net.Conn]struct{})
+	}
+	p.conns[conn] = struct{}{}
+	return func() {
+		p.connsMu.Lock()
+		defer p.connsMu.Unlock()
+		delete(p.conns, conn)
+	}
+}
+
+// proxyTarget is an environment whose proxy is served by the current process.
+type proxyTarget struct {
+	name    string
+	address string
+	dialer  Dialer
+	socks5  bool
+	// stop is called once the proxy no longer accepts connections, it may be nil
+	stop func()
+}
 
-		logger.Warn().Msgf("Proxy for %s listening at %s", opts.Environment.Environment, listen)
-		if !opts.Background {
-			WritePidFile(opts.Environment.Environment, os.Getpid())
+// Listen starts a proxy server that listens on the given address and port, until the context is cancelled.
+// It returns the error of the execution if any.
+func Listen(streams userio.IOStreams, opts EnvConnectOpts, ctx context.Context, listen string, dialer Dialer, execute func() error) error {
+	return serveProxies(streams, opts, ctx, []proxyTarget{{
+		name:    opts.Environment.Environment,
+		address: listen,
+		dialer:  dialer,
+		socks5:  opts.Socks5,
+	}}, execute)
+}
+
+// serveProxies serves the proxies for all the targets until each of them is shut down, the context is cancelled,
+// or execute finishes, in which case its error is returned.
+// Once a proxy stops accepting connections, the active ones are drained before its control socket is removed.
+// In the background the parent checks the tunnels and starts a single child process, which binds the listeners
+// of all the targets once it received the handshake of the parent.
+func serveProxies(streams userio.IOStreams, opts EnvConnectOpts, ctx context.Context, targets []proxyTarget, execute func() error) error {
+	if IsConnectStartup(opts) { // Common code for foreground and background
+		for _, target := range targets {
+			logger.Info().Msgf("Testing tunnel connection for %s", target.name)
+			if err := testConn(ctx, target.dialer); err != nil {
+				return fmt.Errorf("failed to test connection for %s: %w", target.name, err)
+			}
+			logger.Info().Msgf("Tunnel connection for %s succeeded", target.name)
 		}
 	}
 
 	if IsConnectParent(opts) {
 		// background parent specific logic
-		file, err := listener.(*net.TCPListener).File()
+		names := make([]string, len(targets))
+		for i, target := range targets {
+			names[i] = target.name
+		}
+		pid, err := startBackground(names)
 		if err != nil {
-			logger.Fatal().With(zap.Error(err)).Msg("failed to get file descriptor")
+			return err
 		}
-		if err := listener.Close(); err != nil {
-			logger.Fatal().With(zap.Error(err)).Msg("failed to close listener")
+		for _, target := range targets {
+			logger.Warn().Msgf("Proxy for %s listening at %s in the background with pid %d", target.name, target.address, pid)
 		}
+		return nil
+	}
+	if IsConnectChild(opts) {
+		// background child specific logic, only the environments of the handshake are served
+		for _, target := range targets {
+			served, err := servedInBackground(target.name)
+			if err != nil {
+				return err
+			}
+			if !served {
+				return fmt.Errorf("%s was not handed over by the parent process", target.name)
+			}
+		}
+	}
 
-		cmd := exec.Command(os.Args[0], os.Args[1:]...)
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = os.Stderr
-		cmd.Env = append(os.Environ(), SetBackgroundEnv())
-		cmd.ExtraFiles = []*os.File{file}
-		err = cmd.Start()
+	listeners := make([]net.Listener, len(targets))
+	closeListeners := func() {
+		for _, listener := range listeners {
+			if listener != nil {
+				_ = listener.Close()
+			}
+		}
+	}
+	for i, target := range targets {
+		logger.Info().Msgf("Binding to %s", target.address)
+		listener, err := net.Listen("tcp", target.address)
 		if err != nil {
-			logger.Fatal().With(zap.Error(err)).Msg("failed to start background process")
+			closeListeners()
+			return fmt.Errorf("failed to bind to %s: %w", target.address, err)
 		}
-		WritePidFile(opts.Environment.Environment, cmd.Process.Pid)
-		logger.Warn().Msgf("Proxy for %s running in the background with pid %d",
-			opts.Environment.Environment, cmd.Process.Pid)
+		listeners[i] = listener
 
-		return
+		if target.socks5 {
+			logger.Warn().Msgf("SOCKS5 proxy for %s listening at %s", target.name, target.address)
+		} else {
+			logger.Warn().Msgf("Proxy for %s listening at %s", target.name, target.address)
+		}
+	}
+
+	states := make([]*proxyState, len(targets))
+	for i, target := range targets {
+		listener := listeners[i]
+		states[i] = &proxyState{
+			name:     target.name,
+			address:  target.address,
+			dialer:   target.dialer,
+			socks5:   target.socks5,
+			shutdown: func() { _ = listener.Close() },
+		}
+	}
+	if opts.MetricsPort != 0 {
+		stopMetrics, err := serveMetrics(proxyAddress(opts.MetricsPort), states)
+		if err != nil {
+			closeListeners()
+			return err
+		}
+		defer stopMetrics()
 	}
-	if IsConnectChild(opts) {
-		// background child specific logic
-		fileListener := os.NewFile(uintptr(3), "listener")
 
-		listener, err = net.FileListener(fileListener)
+	var wg sync.WaitGroup
+	errs := make([]error, len(targets))
+	for i, target := range targets {
+		listener := listeners[i]
+		state := states[i]
+		control, err := serveControl(state)
 		if err != nil {
-			logger.Fatal().With(zap.Error(err)).Msg("failed to create listener from file descriptor")
+			closeListeners()
+			wg.Wait()
+			return fmt.Errorf("failed to create control socket for %s: %w", target.name, err)
 		}
-		_ = fileListener.Close()
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer control.Close()
+			if target.stop != nil {
+				defer target.stop()
+			}
+			errs[i] = errors.Join(state.serve(ctx, listener), state.drain(drainTimeout))
+		}()
 	}
 
-	executionFinished := make(chan error)
+	// Stop accepting connections once the context is cancelled, e.g. when the process is signalled
+	served := make(chan struct{})
 	go func() {
-		if execute != nil {
+		select {
+		case <-ctx.Done():
+			logger.Warn().Msg("Shutting down, no longer accepting new connections.")
+			closeListeners()
+		case <-served:
+		}
+	}()
+
+	executionFinished := make(chan error, 1)
+	if execute != nil {
+		go func() {
 			err := execute()
-			_ = listener.Close()
+			logger.Warn().Msg("Execution finished, no longer accepting new connections.")
+			closeListeners()
 			executionFinished <- err
+		}()
+	}
+
+	wg.Wait()
+	close(served)
+	logger.Warn().Msg("Tunnel closed")
+	if execute != nil {
+		if err := <-executionFinished; err != nil {
+			return err
 		}
-	}()
+	}
+	return errors.Join(errs...)
+}
 
+// serve accepts connections until the listener is closed.
+func (p *proxyState) serve(ctx context.Context, listener net.Listener) error {
 	for {
-		select {
-		case <-executionFinished:
-			logger.Warn().Msg("Execution finished, no longer accepting new connections.")
-			return
-		default:
-			conn, err := listener.Accept()
-			if err != nil {
-				if opErr, ok := err.(*net.OpError); ok && opErr.Err.Error() == "use of closed network connection" {
-					logger.Warn().Msg("Listener closed, stopping new connections.")
-					err := <-executionFinished
-					if err != nil {
-						logger.Fatal().With(zap.Error(err)).Msg("Execution failed")
-					}
-					logger.Warn().Msg("Tunnel closed")
-					return
-				}
-				logger.Fatal().With(zap.Error(err)).Msg("failed to accept connection")
+		conn, err := listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				logger.Warn().Msgf("Listener for %s closed, stopping new connections.", p.name)
+				return nil
+			}
+			_ = listener.Close()
+			return fmt.Errorf("failed to accept connection for %s: %w", p.name, err)
+		}
+
+		p.active.Add(1)
+		p.total.Add(1)
+		p.handlers.Add(1)
+		untrack := p.track(conn)
+		go func() {
+			defer p.handlers.Done()
+			defer untrack()
+			defer p.active.Add(-1)
+			if p.socks5 {
+				handleSocksClient(ctx, p, conn)
+			} else {
+				handleClient(ctx, p, conn)
 			}
+		}()
+	}
+}
+
+// drain waits for the active connections to finish, closing the ones still active after the timeout.
+func (p *proxyState) drain(timeout time.Duration) error {
+	drained := make(chan struct{})
+	go func() {
+		p.handlers.Wait()
+		close(drained)
+	}()
+	if active := p.active.Load(); active > 0 {
+		logger.Warn().Msgf("Waiting up to %s for %d active connections of %s to finish", timeout, active, p.name)
+	}
 
-			go handleClient(ctx, dialOpts, conn)
+	select {
+	case <-drained:
+		return nil
+	case <-time.After(timeout):
+	}
+	active := p.active.Load()
+	p.connsMu.Lock()
+	conns := slices.Collect(maps.Keys(p.conns))
+	p.connsMu.Unlock()
+	// closing untracks the connections, so it can't be done while holding the lock
+	for _, conn := range conns {
+		_ = conn.Close()
+	}
+	<-drained
+	return fmt.Errorf("%w: closed %d connections of %s", ErrDrainTimeout, active, p.name)
+}
+
+// waitForControlSocket waits until the proxy for the named environment answers on its control socket.
+func waitForControlSocket(name string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		_, err := QueryProxy(name, ControlStatus)
+		if err == nil {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return err
 		}
+		time.Sleep(100 * time.Millisecond)
 	}
 }
 
-func testConn(ctx context.Context, opts []iap.DialOption) error {
-	tun, err := iap.Dial(ctx, opts...)
+func testConn(ctx context.Context, dialer Dialer) error {
+	tun, err := dialer.Dial(ctx)
 	if tun != nil {
 		defer func() { _ = tun.Close() }()
 	}
 	return err
 }
 
-func handleClient(ctx context.Context, opts []iap.DialOption, conn net.Conn) {
+// handleClient forwards the client connection through a new tunnel connection until either side closes it.
+// It returns the number of bytes sent to and received from the tunnel.
+func handleClient(ctx context.Context, dialer Dialer, conn net.Conn) (sent int64, received int64) {
+	defer func() { _ = conn.Close() }()
 	logger.Debug().Msgf("connected: client %s", conn.RemoteAddr())
 
-	tun, err := iap.Dial(ctx, opts...)
+	tun, err := dialer.Dial(ctx)
 	if err != nil {
-		logger.Error().With(zap.Error(err)).Msgf("Failed to connect to IAP for client: %s", conn.RemoteAddr())
-		return
+		logger.Error().With(zap.Error(err)).Msgf("Failed to open tunnel for client: %s", conn.RemoteAddr())
+		return 0, 0
 	}
 	defer func() { _ = tun.Close() }()
 
-	logger.Debug().Msgf("iap dialed: client %s | %s -> %s (local)", conn.RemoteAddr(), tun.RemoteAddr(), tun.LocalAddr())
+	logger.Debug().Msgf("tunnel dialed: client %s | %s -> %s (local)", conn.RemoteAddr(), tun.RemoteAddr(), tun.LocalAddr())
 
+	sent, received = relay(conn, tun)
+
+	logger.Debug().Msgf("disconnected: client %s | sentbytes %d | recvbytes %d", conn.RemoteAddr(), sent, received)
+	return sent, received
+}
+
+// relay copies data both ways between the client and the tunnel until either side closes its connection.
+func relay(conn net.Conn, tun net.Conn) (sent int64, received int64) {
+	receivedCh := make(chan int64)
 	go func() {
-		if _, err := io.Copy(conn, tun); err != nil {
+		n, err := io.Copy(conn, tun)
+		if err != nil {
 			logger.Debug().With(zap.Error(err)).Msg("failed to transfer data")
 		}
+		receivedCh <- n
 	}()
-	if _, err := io.Copy(tun, conn); err != nil {
+	sent, err := io.Copy(tun, conn)
+	if err != nil {
 		logger.Debug().With(zap.Error(err)).Msg("")
 	}
+	// Let the remote side finish its response if the tunnel supports half-close, otherwise stop receiving
+	if closer, ok := tun.(interface{ CloseWrite() error }); ok {
+		_ = closer.CloseWrite()
+	} else {
+		_ = tun.Close()
+	}
+	received = <-receivedCh
 
-	logger.Debug().Msgf("disconnected: client %s | sentbytes %d | recvbytes %d", conn.RemoteAddr(), tun.Sent(), tun.Received())
+	return sent, received
+}
+
+// countingConn counts the bytes written to and read from the tunnel connection.
+type countingConn struct {
+	net.Conn
+	sent     *atomic.Uint64
+	received *atomic.Uint64
+	// untrack is called once the connection is closed, it may be nil
+	untrack func()
+}
+
+func (c *countingConn) Close() error {
+	if c.untrack != nil {
+		c.untrack()
+	}
+	return c.Conn.Close()
+}
+
+func (c *countingConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	c.received.Add(uint64(n))
+	return n, err
+}
+
+func (c *countingConn) Write(b []byte) (int, error) {
+	n, err := c.Conn.Write(b)
+	c.sent.Add(uint64(n))
+	return n, err
+}
+
+// CloseWrite half-closes the underlying connection when it supports it.
+func (c *countingConn) CloseWrite() error {
+	if closer, ok := c.Conn.(interface{ CloseWrite() error }); ok {
+		return closer.CloseWrite()
+	}
+	return c.Conn.Close()
 }
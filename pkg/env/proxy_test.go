@@ -0,0 +1,212 @@
+package env
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/coreeng/core-platform/pkg/environment"
+	"github.com/coreeng/corectl/pkg/cmdutil/configpath"
+	"github.com/coreeng/corectl/pkg/cmdutil/userio"
+	"github.com/stretchr/testify/assert"
+)
+
+// startEchoServer starts a TCP server which writes back everything it receives
+func startEchoServer(t *testing.T) string {
+	l, err := net.Listen("tcp", "localhost:0")
+	assert.NoError(t, err)
+	t.Cleanup(func() { _ = l.Close() })
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer func() { _ = conn.Close() }()
+				_, _ = io.Copy(conn, conn)
+			}()
+		}
+	}()
+	return l.Addr().String()
+}
+
+func freePort(t *testing.T) int {
+	port, err := freeLocalPort()
+	assert.NoError(t, err)
+	return port
+}
+
+func echo(address string, message string) (string, error) {
+	conn, err := net.Dial("tcp", address)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = conn.Close() }()
+	if _, err := conn.Write([]byte(message)); err != nil {
+		return "", err
+	}
+	if err := conn.(*net.TCPConn).CloseWrite(); err != nil {
+		return "", err
+	}
+	response, err := io.ReadAll(conn)
+	return string(response), err
+}
+
+func TestHandleClientCountsBytes(t *testing.T) {
+	dialer := TCPDialer{Address: startEchoServer(t)}
+	client, proxy := net.Pipe()
+
+	type result struct{ sent, received int64 }
+	done := make(chan result)
+	go func() {
+		sent, received := handleClient(context.Background(), dialer, proxy)
+		done <- result{sent, received}
+	}()
+
+	message := "hello through the tunnel"
+	_, err := client.Write([]byte(message))
+	assert.NoError(t, err)
+	buf := make([]byte, len(message))
+	_, err = io.ReadFull(client, buf)
+	assert.NoError(t, err)
+	assert.Equal(t, message, string(buf))
+	assert.NoError(t, client.Close())
+
+	r := <-done
+	assert.Equal(t, int64(len(message)), r.sent)
+	assert.Equal(t, int64(len(message)), r.received)
+}
+
+func TestHandleClientDialFailure(t *testing.T) {
+	dialer := TCPDialer{Address: fmt.Sprintf("localhost:%d", freePort(t))}
+	client, proxy := net.Pipe()
+	defer func() { _ = client.Close() }()
+
+	sent, received := handleClient(context.Background(), dialer, proxy)
+	assert.Zero(t, sent)
+	assert.Zero(t, received)
+
+	_, err := client.Read(make([]byte, 1))
+	assert.ErrorIs(t, err, io.EOF)
+}
+
+func TestTestConn(t *testing.T) {
+	assert.NoError(t, testConn(context.Background(), TCPDialer{Address: startEchoServer(t)}))
+	assert.Error(t, testConn(context.Background(), TCPDialer{Address: fmt.Sprintf("localhost:%d", freePort(t))}))
+}
+
+func TestListenForwardsUntilExecutionFinished(t *testing.T) {
+	t.Setenv("CORECTL_HOME", t.TempDir())
+	name := "listen-test"
+	bind := fmt.Sprintf("localhost:%d", freePort(t))
+	opts := EnvConnectOpts{
+		Environment: &environment.Environment{Environment: name},
+	}
+	streams := userio.NewIOStreams(os.Stdin, os.Stdout, os.Stderr)
+
+	var responses []string
+	var status *ControlResponse
+	execute := func() error {
+		for _, message := range []string{"first", "second"} {
+			response, err := echo(bind, message)
+			if err != nil {
+				return err
+			}
+			responses = append(responses, response)
+		}
+		var err error
+		status, err = QueryProxy(name, ControlStatus)
+		return err
+	}
+
+	assert.NoError(t, Listen(streams, opts, context.Background(), bind, TCPDialer{Address: startEchoServer(t)}, execute))
+
+	assert.Equal(t, []string{"first", "second"}, responses)
+	assert.Equal(t, os.Getpid(), status.Pid)
+	assert.Equal(t, bind, status.Address)
+
+	_, err := net.Dial("tcp", bind)
+	var opErr *net.OpError
+	assert.True(t, errors.As(err, &opErr), "listener should be closed once execution finished")
+	_, err = os.Stat(controlSocketPath(name))
+	assert.ErrorIs(t, err, os.ErrNotExist, "control socket should be removed once execution finished")
+}
+
+func TestListenAnswersControlRequests(t *testing.T) {
+	t.Setenv("CORECTL_HOME", t.TempDir())
+	name := "control-test"
+	bind := fmt.Sprintf("localhost:%d", freePort(t))
+	opts := EnvConnectOpts{
+		Environment: &environment.Environment{Environment: name},
+	}
+	streams := userio.NewIOStreams(os.Stdin, os.Stdout, os.Stderr)
+
+	stopped := make(chan struct{})
+	go func() {
+		assert.NoError(t, Listen(streams, opts, context.Background(), bind, TCPDialer{Address: startEchoServer(t)}, nil))
+		close(stopped)
+	}()
+	assert.NoError(t, waitForControlSocket(name, 5*time.Second))
+
+	response, err := echo(bind, "hello")
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", response)
+
+	status, err := QueryProxy(name, ControlStatus)
+	assert.NoError(t, err)
+	assert.Equal(t, name, status.Environment)
+	assert.Equal(t, bind, status.Address)
+	assert.Equal(t, "http", status.Scheme)
+	assert.True(t, status.Health.Healthy)
+	assert.Nil(t, status.Stats)
+
+	// the connection is accounted for once the proxy notices the client went away
+	assert.Eventually(t, func() bool {
+		stats, err := QueryProxy(name, ControlStats)
+		return err == nil && stats.Stats.ActiveConnections == 0
+	}, 5*time.Second, 10*time.Millisecond)
+	stats, err := QueryProxy(name, ControlStats)
+	assert.NoError(t, err)
+	assert.Equal(t, ProxyStats{ActiveConnections: 0, TotalConnections: 1, BytesSent: 5, BytesReceived: 5}, *stats.Stats)
+
+	proxyUrl, err := ActiveProxyURL(name)
+	assert.NoError(t, err)
+	assert.Equal(t, "http://"+bind, proxyUrl.String())
+
+	_, err = QueryProxy(name, "unknown")
+	assert.EqualError(t, err, "unknown command: unknown")
+
+	proxies := GetActiveProxies([]environment.Environment{{Environment: name}, {Environment: "not-running"}})
+	assert.Len(t, proxies, 1)
+	assert.Equal(t, bind, proxies[name].Address)
+
+	assert.NoError(t, ShutdownProxy(name))
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("proxy did not stop after shutdown request")
+	}
+	assert.Empty(t, GetActiveProxies([]environment.Environment{{Environment: name}}))
+}
+
+func TestGetActiveProxiesRemovesStaleSockets(t *testing.T) {
+	t.Setenv("CORECTL_HOME", t.TempDir())
+	name := "stale-test"
+	assert.NoError(t, os.MkdirAll(configpath.GetCorectlProxiesDir(), 0700))
+	l, err := net.Listen("unix", controlSocketPath(name))
+	assert.NoError(t, err)
+	// closing a unix listener removes its socket file, so keep a copy around
+	l.(*net.UnixListener).SetUnlinkOnClose(false)
+	assert.NoError(t, l.Close())
+
+	assert.Empty(t, GetActiveProxies([]environment.Environment{{Environment: name}}))
+	_, err = os.Stat(controlSocketPath(name))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
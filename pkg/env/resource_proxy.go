@@ -0,0 +1,118 @@
+package env
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/coreeng/core-platform/pkg/environment"
+	"github.com/coreeng/corectl/pkg/logger"
+)
+
+// ResourceProxyIdleTimeout stops the local proxy of a resource once it hasn't served a request for that long.
+const ResourceProxyIdleTimeout = 10 * time.Minute
+
+// ActiveProxyURL returns the url clients use for the running proxy of the named environment.
+func ActiveProxyURL(name string) (*url.URL, error) {
+	status, err := QueryProxy(name, ControlStatus)
+	if err != nil {
+		return nil, err
+	}
+	scheme := status.Scheme
+	if scheme == "" {
+		scheme = proxyScheme(false)
+	}
+	return &url.URL{Scheme: scheme, Host: status.Address}, nil
+}
+
+// NewResourceProxy forwards the requests it receives to the target through the proxy,
+// rewriting redirects to the target so the browser keeps going through the local address.
+func NewResourceProxy(target *url.URL, proxy *url.URL) *httputil.ReverseProxy {
+	return &httputil.ReverseProxy{
+		Rewrite: func(r *httputil.ProxyRequest) {
+			r.SetURL(target)
+			r.Out.Host = target.Host
+			r.SetXForwarded()
+		},
+		Transport: &http.Transport{
+			Proxy:               http.ProxyURL(proxy),
+			TLSHandshakeTimeout: 10 * time.Second,
+			IdleConnTimeout:     90 * time.Second,
+		},
+		ModifyResponse: func(resp *http.Response) error {
+			location, err := resp.Location()
+			if err != nil || location.Host != target.Host {
+				return nil
+			}
+			location.Scheme = "http"
+			location.Host = resp.Request.Header.Get("X-Forwarded-Host")
+			resp.Header.Set("Location", location.String())
+			return nil
+		},
+	}
+}
+
+// ServeResource serves the local proxy of the resource on the listener until the context is cancelled,
+// or until it has been idle for the idle timeout.
+func ServeResource(ctx context.Context, listener net.Listener, handler http.Handler, idleTimeout time.Duration) error {
+	var lastRequest atomic.Int64
+	lastRequest.Store(time.Now().UnixNano())
+	server := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			lastRequest.Store(time.Now().UnixNano())
+			handler.ServeHTTP(w, r)
+		}),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(min(idleTimeout, time.Second))
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+			case <-ticker.C:
+				if time.Since(time.Unix(0, lastRequest.Load())) < idleTimeout {
+					continue
+				}
+				logger.Warn().Msgf("No request for %s, stopping", idleTimeout)
+			}
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			_ = server.Shutdown(shutdownCtx)
+			return
+		}
+	}()
+
+	if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	<-stopped
+	return nil
+}
+
+// PACFile returns a proxy auto-config file sending the requests to the internal services of the environment
+// through the proxy, and all the others directly.
+func PACFile(env *environment.Environment, proxy *url.URL) string {
+	directive := "PROXY"
+	if proxy.Scheme == "socks5" {
+		directive = "SOCKS5"
+	}
+	domain := strings.TrimPrefix(env.InternalServices.Domain, ".")
+	return fmt.Sprintf(`function FindProxyForURL(url, host) {
+  if (host == "%[1]s" || dnsDomainIs(host, ".%[1]s")) {
+    return "%[2]s %[3]s";
+  }
+  return "DIRECT";
+}
+`, domain, directive, proxy.Host)
+}
@@ -0,0 +1,127 @@
+package env
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/coreeng/core-platform/pkg/environment"
+	"github.com/stretchr/testify/assert"
+)
+
+// startFakeProxy starts an HTTP proxy answering for the internal services itself, as if it forwarded to them
+func startFakeProxy(t *testing.T, handler http.HandlerFunc) *url.URL {
+	proxy := httptest.NewServer(handler)
+	t.Cleanup(proxy.Close)
+	proxyUrl, err := url.Parse(proxy.URL)
+	assert.NoError(t, err)
+	return proxyUrl
+}
+
+func TestResourceProxyForwardsThroughProxy(t *testing.T) {
+	var received *http.Request
+	proxy := startFakeProxy(t, func(w http.ResponseWriter, r *http.Request) {
+		received = r
+		_, _ = w.Write([]byte("dashboards"))
+	})
+	target := &url.URL{Scheme: "http", Host: "grafana.internal.example.com"}
+	local := httptest.NewServer(NewResourceProxy(target, proxy))
+	defer local.Close()
+
+	resp, err := http.Get(local.URL + "/dashboards?query=tenant")
+	assert.NoError(t, err)
+	defer func() { _ = resp.Body.Close() }()
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "dashboards", string(body))
+	assert.Equal(t, "grafana.internal.example.com", received.Host)
+	assert.Equal(t, "http://grafana.internal.example.com/dashboards?query=tenant", received.RequestURI)
+}
+
+func TestResourceProxyRewritesRedirectsToTarget(t *testing.T) {
+	proxy := startFakeProxy(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/elsewhere" {
+			http.Redirect(w, r, "https://login.example.com/", http.StatusFound)
+			return
+		}
+		http.Redirect(w, r, "http://grafana.internal.example.com/login?next=%2F", http.StatusFound)
+	})
+	target := &url.URL{Scheme: "http", Host: "grafana.internal.example.com"}
+	local := httptest.NewServer(NewResourceProxy(target, proxy))
+	defer local.Close()
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+
+	resp, err := client.Get(local.URL + "/")
+	assert.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, local.URL+"/login?next=%2F", resp.Header.Get("Location"))
+
+	resp, err = client.Get(local.URL + "/elsewhere")
+	assert.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, "https://login.example.com/", resp.Header.Get("Location"))
+}
+
+func TestServeResourceStopsWhenIdle(t *testing.T) {
+	listener, err := net.Listen("tcp", "localhost:0")
+	assert.NoError(t, err)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	stopped := make(chan error, 1)
+	go func() {
+		stopped <- ServeResource(context.Background(), listener, handler, 200*time.Millisecond)
+	}()
+
+	resp, err := http.Get("http://" + listener.Addr().String())
+	assert.NoError(t, err)
+	_ = resp.Body.Close()
+	select {
+	case err := <-stopped:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("resource proxy did not stop once idle")
+	}
+}
+
+func TestServeResourceStopsWhenCancelled(t *testing.T) {
+	listener, err := net.Listen("tcp", "localhost:0")
+	assert.NoError(t, err)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	ctx, cancel := context.WithCancel(context.Background())
+
+	stopped := make(chan error, 1)
+	go func() {
+		stopped <- ServeResource(ctx, listener, handler, time.Hour)
+	}()
+	cancel()
+
+	select {
+	case err := <-stopped:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("resource proxy did not stop once cancelled")
+	}
+}
+
+func TestPACFile(t *testing.T) {
+	env := &environment.Environment{
+		InternalServices: environment.Domain{Domain: "internal.example.com"},
+	}
+
+	pac := PACFile(env, &url.URL{Scheme: "http", Host: "localhost:36000"})
+	assert.Contains(t, pac, `dnsDomainIs(host, ".internal.example.com")`)
+	assert.Contains(t, pac, `return "PROXY localhost:36000";`)
+	assert.Contains(t, pac, `return "DIRECT";`)
+
+	pac = PACFile(env, &url.URL{Scheme: "socks5", Host: "localhost:36000"})
+	assert.Contains(t, pac, `return "SOCKS5 localhost:36000";`)
+}
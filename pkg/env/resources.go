@@ -1,44 +1,158 @@
 package env
 
 import (
+	"bytes"
 	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"text/template"
 
 	"github.com/coreeng/core-platform/pkg/environment"
+	"gopkg.in/yaml.v3"
 )
 
 var (
 	ErrorUnknownResourceType = errors.New("unknown resource type")
+	ErrResourceNeedsTenant   = errors.New("resource is scoped to a tenant, a tenant is required")
 )
 
-type ResourceType string
-
 const (
 	GrafanaResourceType               = "grafana"
 	GrafanaContinuousLoadResourceType = "grafana/continuous-load"
+	GrafanaTenantResourceType         = "grafana/tenant"
 )
 
-func ResourceStringList() []string {
-	return []string{
-		GrafanaResourceType,
-		GrafanaContinuousLoadResourceType,
+// ResourcesFile is the file of the environments repository defining extra resources for all the environments.
+const ResourcesFile = "resources.yaml"
+
+// Resource is a link to a web resource of an environment.
+// Its URL is a template over the fields of the environment, with the tenant given by the tenant function
+// for tenant scoped resources, e.g. `https://grafana.{{ .InternalServices.Domain }}/dashboards?query={{ tenant }}`.
+type Resource struct {
+	Name        string `yaml:"name"`
+	Description string `yaml:"description"`
+	URL         string `yaml:"url"`
+	// Tenant scoped resources link to the part of the resource for a single tenant
+	Tenant bool `yaml:"tenant,omitempty"`
+}
+
+// DefaultResources are the resources available for all the environments.
+func DefaultResources() []Resource {
+	return []Resource{
+		{
+			Name:        GrafanaResourceType,
+			Description: "Grafana of the environment",
+			URL:         "https://grafana.{{ .InternalServices.Domain }}",
+		},
+		{
+			Name:        GrafanaContinuousLoadResourceType,
+			Description: "Continuous load dashboard",
+			URL:         "https://grafana.{{ .InternalServices.Domain }}/d/zDpLnqaMz/continuous-load?orgId=1&refresh=5s",
+		},
+		{
+			Name:        GrafanaTenantResourceType,
+			Description: "Grafana dashboards of the tenant",
+			URL:         "https://grafana.{{ .InternalServices.Domain }}/dashboards?query={{ tenant }}",
+			Tenant:      true,
+		},
 	}
 }
 
-func OpenResource(resourceType ResourceType, env *environment.Environment) (string, error) {
-	switch resourceType {
-	case GrafanaResourceType:
-		return openGrafana(env), nil
-	case GrafanaContinuousLoadResourceType:
-		return openGrafanaContinuousLoad(env), nil
-	default:
-		return "", ErrorUnknownResourceType
+// LoadResources reads the resources defined in the file, a missing file defines none.
+func LoadResources(path string) ([]Resource, error) {
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read resources: %w", err)
+	}
+	var resources []Resource
+	if err := yaml.Unmarshal(content, &resources); err != nil {
+		return nil, fmt.Errorf("parse resources %s: %w", path, err)
+	}
+	for _, r := range resources {
+		if err := r.validate(); err != nil {
+			return nil, fmt.Errorf("invalid resource in %s: %w", path, err)
+		}
 	}
+	return resources, nil
 }
 
-func openGrafana(env *environment.Environment) string {
-	return "https://grafana." + env.InternalServices.Domain
+// MergeResources combines the sets of resources, sorted by name.
+// Resources of later sets replace the resources with the same name of earlier ones.
+func MergeResources(sets ...[]Resource) []Resource {
+	byName := map[string]Resource{}
+	for _, set := range sets {
+		for _, r := range set {
+			byName[r.Name] = r
+		}
+	}
+	resources := make([]Resource, 0, len(byName))
+	for _, r := range byName {
+		resources = append(resources, r)
+	}
+	slices.SortFunc(resources, func(a, b Resource) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return resources
+}
+
+// FindResource returns the resource with the given name.
+func FindResource(resources []Resource, name string) (Resource, error) {
+	for _, r := range resources {
+		if r.Name == name {
+			return r, nil
+		}
+	}
+	return Resource{}, fmt.Errorf("%w: %s", ErrorUnknownResourceType, name)
 }
 
-func openGrafanaContinuousLoad(env *environment.Environment) string {
-	return "https://grafana." + env.InternalServices.Domain + "/d/zDpLnqaMz/continuous-load?orgId=1&refresh=5s"
+// ResourceNames returns the names of the resources.
+func ResourceNames(resources []Resource) []string {
+	names := make([]string, len(resources))
+	for i, r := range resources {
+		names[i] = r.Name
+	}
+	return names
+}
+
+// URLFor renders the URL of the resource for the environment. Tenant scoped resources need a tenant.
+func (r Resource) URLFor(env *environment.Environment, tenant string) (string, error) {
+	if r.Tenant && tenant == "" {
+		return "", fmt.Errorf("%s: %w", r.Name, ErrResourceNeedsTenant)
+	}
+	tmpl, err := r.template(tenant)
+	if err != nil {
+		return "", err
+	}
+	var url bytes.Buffer
+	if err := tmpl.Execute(&url, env); err != nil {
+		return "", fmt.Errorf("render url of resource %s: %w", r.Name, err)
+	}
+	return url.String(), nil
+}
+
+func (r Resource) template(tenant string) (*template.Template, error) {
+	tmpl, err := template.New(r.Name).
+		Option("missingkey=error").
+		Funcs(template.FuncMap{"tenant": func() string { return tenant }}).
+		Parse(r.URL)
+	if err != nil {
+		return nil, fmt.Errorf("parse url of resource %s: %w", r.Name, err)
+	}
+	return tmpl, nil
+}
+
+func (r Resource) validate() error {
+	if r.Name == "" {
+		return errors.New("resource has no name")
+	}
+	if r.URL == "" {
+		return fmt.Errorf("resource %s has no url", r.Name)
+	}
+	_, err := r.template("")
+	return err
 }
@@ -0,0 +1,87 @@
+package env
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/coreeng/core-platform/pkg/environment"
+	"github.com/stretchr/testify/assert"
+)
+
+var resourcesTestEnv = &environment.Environment{
+	Environment:      "predev",
+	InternalServices: environment.Domain{Domain: "predev.internal.example.com"},
+	Platform:         &environment.GCPVendor{ProjectId: "gcp-predev-1234", Region: "europe-west2"},
+}
+
+func TestDefaultResourcesURL(t *testing.T) {
+	tests := []struct {
+		name     string
+		tenant   string
+		expected string
+	}{
+		{name: GrafanaResourceType, expected: "https://grafana.predev.internal.example.com"},
+		{name: GrafanaContinuousLoadResourceType, expected: "https://grafana.predev.internal.example.com/d/zDpLnqaMz/continuous-load?orgId=1&refresh=5s"},
+		{name: GrafanaTenantResourceType, tenant: "payments", expected: "https://grafana.predev.internal.example.com/dashboards?query=payments"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resource, err := FindResource(DefaultResources(), tt.name)
+			assert.NoError(t, err)
+			url, err := resource.URLFor(resourcesTestEnv, tt.tenant)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, url)
+		})
+	}
+}
+
+func TestResourceURLFor(t *testing.T) {
+	resource := Resource{Name: "console", URL: "https://console.cloud.google.com/home/dashboard?project={{ .Platform.ProjectId }}&env={{ .Environment }}"}
+	url, err := resource.URLFor(resourcesTestEnv, "")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://console.cloud.google.com/home/dashboard?project=gcp-predev-1234&env=predev", url)
+
+	tenantResource := Resource{Name: "logs", URL: "https://logs/{{ tenant }}", Tenant: true}
+	_, err = tenantResource.URLFor(resourcesTestEnv, "")
+	assert.ErrorIs(t, err, ErrResourceNeedsTenant)
+
+	_, err = Resource{Name: "broken", URL: "https://{{ .Missing }}"}.URLFor(resourcesTestEnv, "")
+	assert.ErrorContains(t, err, "render url of resource broken")
+
+	_, err = FindResource(DefaultResources(), "unknown")
+	assert.ErrorIs(t, err, ErrorUnknownResourceType)
+}
+
+func TestLoadAndMergeResources(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ResourcesFile)
+	resources, err := LoadResources(path)
+	assert.NoError(t, err)
+	assert.Empty(t, resources)
+
+	assert.NoError(t, os.WriteFile(path, []byte(`
+- name: argocd
+  description: ArgoCD
+  url: https://argocd.{{ .InternalServices.Domain }}
+- name: grafana
+  description: Grafana home
+  url: https://grafana.{{ .InternalServices.Domain }}/home
+`), 0o600))
+	repoResources, err := LoadResources(path)
+	assert.NoError(t, err)
+
+	cfgResources := []Resource{{Name: "argocd", URL: "https://argo.{{ .InternalServices.Domain }}"}}
+	merged := MergeResources(DefaultResources(), repoResources, cfgResources)
+	assert.Equal(t, []string{"argocd", GrafanaResourceType, GrafanaContinuousLoadResourceType, GrafanaTenantResourceType}, ResourceNames(merged))
+
+	argocd, err := FindResource(merged, "argocd")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://argo.{{ .InternalServices.Domain }}", argocd.URL)
+	grafana, err := FindResource(merged, GrafanaResourceType)
+	assert.NoError(t, err)
+	assert.Equal(t, "Grafana home", grafana.Description)
+
+	assert.NoError(t, os.WriteFile(path, []byte(`- name: nourl`), 0o600))
+	_, err = LoadResources(path)
+	assert.ErrorContains(t, err, "resource nourl has no url")
+}
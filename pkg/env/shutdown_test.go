@@ -0,0 +1,176 @@
+package env
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/coreeng/core-platform/pkg/environment"
+	"github.com/coreeng/corectl/pkg/cmdutil/userio"
+	"github.com/stretchr/testify/assert"
+)
+
+// pipeDialer is a fake tunnel whose remote side is handled in memory
+type pipeDialer struct {
+	handle func(remote net.Conn)
+}
+
+func (d pipeDialer) Dial(ctx context.Context) (net.Conn, error) {
+	local, remote := net.Pipe()
+	go d.handle(remote)
+	return local, nil
+}
+
+// startTestProxy serves a proxy for the named environment through the dialer until the returned context is cancelled,
+// sending the result of Listen on the returned channel
+func startTestProxy(t *testing.T, name string, dialer Dialer) (string, context.CancelFunc, chan error) {
+	t.Setenv("CORECTL_HOME", t.TempDir())
+	bind := fmt.Sprintf("localhost:%d", freePort(t))
+	opts := EnvConnectOpts{Environment: &environment.Environment{Environment: name}}
+	streams := userio.NewIOStreams(os.Stdin, os.Stdout, os.Stderr)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	stopped := make(chan error, 1)
+	go func() {
+		stopped <- Listen(streams, opts, ctx, bind, dialer, nil)
+	}()
+	assert.NoError(t, waitForControlSocket(name, 5*time.Second))
+	return bind, cancel, stopped
+}
+
+func TestListenDrainsConnectionsOnShutdown(t *testing.T) {
+	received := make(chan struct{}, 1)
+	release := make(chan struct{})
+	dialer := pipeDialer{handle: func(remote net.Conn) {
+		defer func() { _ = remote.Close() }()
+		request := make([]byte, 5)
+		if _, err := io.ReadFull(remote, request); err != nil {
+			return
+		}
+		received <- struct{}{}
+		<-release
+		_, _ = remote.Write(request)
+	}}
+	name := "drain-test"
+	bind, cancel, stopped := startTestProxy(t, name, dialer)
+
+	conn, err := net.Dial("tcp", bind)
+	assert.NoError(t, err)
+	defer func() { _ = conn.Close() }()
+	_, err = conn.Write([]byte("hello"))
+	assert.NoError(t, err)
+	<-received
+
+	cancel()
+	assert.Eventually(t, func() bool {
+		probe, err := net.Dial("tcp", bind)
+		if err != nil {
+			return true
+		}
+		_ = probe.Close()
+		return false
+	}, 5*time.Second, 10*time.Millisecond, "no new connection should be accepted once shutting down")
+
+	// the connection in flight completes
+	close(release)
+	response := make([]byte, 5)
+	_, err = io.ReadFull(conn, response)
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", string(response))
+	_ = conn.Close()
+
+	select {
+	case err := <-stopped:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("proxy did not stop once its connections were drained")
+	}
+	_, err = os.Stat(controlSocketPath(name))
+	assert.ErrorIs(t, err, os.ErrNotExist, "control socket should be removed once stopped")
+}
+
+func TestListenClosesConnectionsAfterDrainTimeout(t *testing.T) {
+	timeout := drainTimeout
+	drainTimeout = 100 * time.Millisecond
+	t.Cleanup(func() { drainTimeout = timeout })
+
+	received := make(chan struct{}, 1)
+	dialer := pipeDialer{handle: func(remote net.Conn) {
+		defer func() { _ = remote.Close() }()
+		request := make([]byte, 5)
+		if _, err := io.ReadFull(remote, request); err != nil {
+			return
+		}
+		received <- struct{}{}
+		// never answers, until the tunnel is closed
+		_, _ = io.Copy(io.Discard, remote)
+	}}
+	name := "drain-timeout-test"
+	bind, cancel, stopped := startTestProxy(t, name, dialer)
+
+	conn, err := net.Dial("tcp", bind)
+	assert.NoError(t, err)
+	defer func() { _ = conn.Close() }()
+	_, err = conn.Write([]byte("hello"))
+	assert.NoError(t, err)
+	<-received
+
+	cancel()
+	select {
+	case err := <-stopped:
+		assert.ErrorIs(t, err, ErrDrainTimeout)
+		assert.ErrorContains(t, err, "closed 1 connections of drain-timeout-test")
+	case <-time.After(5 * time.Second):
+		t.Fatal("proxy did not stop once the drain timed out")
+	}
+	_, err = conn.Read(make([]byte, 1))
+	assert.ErrorIs(t, err, io.EOF, "the connection still active should be closed")
+	_, err = os.Stat(controlSocketPath(name))
+	assert.ErrorIs(t, err, os.ErrNotExist, "control socket should be removed once stopped")
+}
+
+func TestListenFailsWhenTheTunnelCannotBeOpened(t *testing.T) {
+	t.Setenv("CORECTL_HOME", t.TempDir())
+	opts := EnvConnectOpts{Environment: &environment.Environment{Environment: "unreachable"}}
+	streams := userio.NewIOStreams(os.Stdin, os.Stdout, os.Stderr)
+	dialer := TCPDialer{Address: fmt.Sprintf("localhost:%d", freePort(t))}
+
+	err := Listen(streams, opts, context.Background(), fmt.Sprintf("localhost:%d", freePort(t)), dialer, nil)
+	assert.ErrorContains(t, err, "failed to test connection for unreachable")
+}
+
+func TestShutdownProxyWaitsForTheDrain(t *testing.T) {
+	received := make(chan struct{}, 1)
+	dialer := pipeDialer{handle: func(remote net.Conn) {
+		defer func() { _ = remote.Close() }()
+		request := make([]byte, 5)
+		if _, err := io.ReadFull(remote, request); err != nil {
+			return
+		}
+		received <- struct{}{}
+		// answers after the control timeout, within the drain timeout
+		time.Sleep(controlTimeout + 500*time.Millisecond)
+		_, _ = remote.Write(request)
+	}}
+	name := "shutdown-drain-test"
+	bind, _, stopped := startTestProxy(t, name, dialer)
+
+	conn, err := net.Dial("tcp", bind)
+	assert.NoError(t, err)
+	defer func() { _ = conn.Close() }()
+	_, err = conn.Write([]byte("hello"))
+	assert.NoError(t, err)
+	<-received
+
+	go func() {
+		_, _ = io.ReadFull(conn, make([]byte, 5))
+		_ = conn.Close()
+	}()
+	assert.NoError(t, ShutdownProxy(name))
+	assert.NoError(t, <-stopped)
+}
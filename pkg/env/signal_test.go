@@ -0,0 +1,46 @@
+//go:build !windows
+
+package env
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/coreeng/core-platform/pkg/environment"
+	"github.com/coreeng/corectl/pkg/cmdutil/userio"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStartTunnelsReturnsTheSignalStoppingThem(t *testing.T) {
+	t.Setenv("CORECTL_HOME", t.TempDir())
+	t.Setenv("KUBECONFIG", filepath.Join(t.TempDir(), "config"))
+	name := fmt.Sprintf("signal-test-%d", os.Getpid())
+	opts := EnvConnectOpts{
+		Streams:     userio.NewIOStreams(os.Stdin, os.Stdout, os.Stderr),
+		Environment: &environment.Environment{Environment: name},
+		Port:        freePort(t),
+		Provider:    &fakeProvider{dialer: TCPDialer{Address: startEchoServer(t)}},
+	}
+
+	stopped := make(chan error, 1)
+	go func() {
+		stopped <- startTunnels(opts, []EnvConnectOpts{opts}, nil)
+	}()
+	assert.NoError(t, waitForControlSocket(name, 5*time.Second))
+	assert.NoError(t, syscall.Kill(os.Getpid(), syscall.SIGHUP))
+
+	select {
+	case err := <-stopped:
+		var signalErr SignalExitError
+		assert.ErrorAs(t, err, &signalErr)
+		assert.Equal(t, syscall.SIGHUP, signalErr.Signal)
+		assert.NoError(t, signalErr.Err)
+		assert.Equal(t, 129, signalErr.Code())
+	case <-time.After(5 * time.Second):
+		t.Fatal("proxy did not stop when signalled")
+	}
+}
@@ -0,0 +1,215 @@
+package env
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/coreeng/corectl/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// SOCKS5 protocol constants, see RFC 1928
+const (
+	socks5Version = 0x05
+
+	socks5AuthNone          = 0x00
+	socks5AuthNotAcceptable = 0xff
+
+	socks5CmdConnect = 0x01
+
+	socks5AddrIPv4   = 0x01
+	socks5AddrDomain = 0x03
+	socks5AddrIPv6   = 0x04
+
+	socks5ReplySucceeded        = 0x00
+	socks5ReplyGeneralFailure   = 0x01
+	socks5ReplyNotAllowed       = 0x02
+	socks5ReplyHostUnreachable  = 0x04
+	socks5ReplyCmdNotSupported  = 0x07
+	socks5ReplyAddrNotSupported = 0x08
+)
+
+// socks5Error is a failed SOCKS5 negotiation, which is reported to the client with the reply code.
+type socks5Error struct {
+	reply byte
+	err   error
+}
+
+func (e *socks5Error) Error() string {
+	return e.err.Error()
+}
+
+// handleSocksClient negotiates a SOCKS5 CONNECT with the client and forwards it through an HTTP CONNECT
+// to the squid proxy at the other end of the tunnel, until either side closes the connection.
+func handleSocksClient(ctx context.Context, dialer Dialer, conn net.Conn) {
+	defer func() { _ = conn.Close() }()
+	logger.Debug().Msgf("connected: socks5 client %s", conn.RemoteAddr())
+
+	target, err := socks5Handshake(conn)
+	if err != nil {
+		logger.Debug().With(zap.Error(err)).Msgf("socks5 negotiation with %s failed", conn.RemoteAddr())
+		// Only a well-formed request is answered with a reply
+		var socksErr *socks5Error
+		if errors.As(err, &socksErr) {
+			socks5Reply(conn, err)
+		}
+		return
+	}
+
+	tun, err := dialer.Dial(ctx)
+	if err != nil {
+		logger.Error().With(zap.Error(err)).Msgf("Failed to open tunnel for client: %s", conn.RemoteAddr())
+		socks5Reply(conn, &socks5Error{reply: socks5ReplyGeneralFailure, err: err})
+		return
+	}
+	defer func() { _ = tun.Close() }()
+
+	connected, err := httpConnect(tun, target)
+	if err != nil {
+		logger.Debug().With(zap.Error(err)).Msgf("socks5 client %s could not connect to %s", conn.RemoteAddr(), target)
+		socks5Reply(conn, err)
+		return
+	}
+	socks5Reply(conn, nil)
+
+	logger.Debug().Msgf("tunnel dialed: socks5 client %s -> %s", conn.RemoteAddr(), target)
+	sent, received := relay(conn, connected)
+	logger.Debug().Msgf("disconnected: socks5 client %s | %s | sentbytes %d | recvbytes %d", conn.RemoteAddr(), target, sent, received)
+}
+
+// socks5Handshake reads the method selection and the request of the client and returns the requested address.
+// Only unauthenticated CONNECT requests are supported.
+func socks5Handshake(conn net.Conn) (string, error) {
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return "", fmt.Errorf("read socks5 greeting: %w", err)
+	}
+	if header[0] != socks5Version {
+		return "", fmt.Errorf("unsupported socks version %d", header[0])
+	}
+	methods := make([]byte, header[1])
+	if _, err := io.ReadFull(conn, methods); err != nil {
+		return "", fmt.Errorf("read socks5 methods: %w", err)
+	}
+	method := byte(socks5AuthNotAcceptable)
+	for _, m := range methods {
+		if m == socks5AuthNone {
+			method = socks5AuthNone
+		}
+	}
+	if _, err := conn.Write([]byte{socks5Version, method}); err != nil {
+		return "", fmt.Errorf("write socks5 method: %w", err)
+	}
+	if method == socks5AuthNotAcceptable {
+		return "", errors.New("socks5 client does not support unauthenticated connections")
+	}
+
+	request := make([]byte, 4)
+	if _, err := io.ReadFull(conn, request); err != nil {
+		return "", fmt.Errorf("read socks5 request: %w", err)
+	}
+
+	var host string
+	switch request[3] {
+	case socks5AddrIPv4, socks5AddrIPv6:
+		ip := make(net.IP, net.IPv4len)
+		if request[3] == socks5AddrIPv6 {
+			ip = make(net.IP, net.IPv6len)
+		}
+		if _, err := io.ReadFull(conn, ip); err != nil {
+			return "", fmt.Errorf("read socks5 address: %w", err)
+		}
+		host = ip.String()
+	case socks5AddrDomain:
+		length := make([]byte, 1)
+		if _, err := io.ReadFull(conn, length); err != nil {
+			return "", fmt.Errorf("read socks5 address: %w", err)
+		}
+		domain := make([]byte, length[0])
+		if _, err := io.ReadFull(conn, domain); err != nil {
+			return "", fmt.Errorf("read socks5 address: %w", err)
+		}
+		host = string(domain)
+	default:
+		return "", &socks5Error{reply: socks5ReplyAddrNotSupported, err: fmt.Errorf("unsupported socks5 address type %d", request[3])}
+	}
+
+	port := make([]byte, 2)
+	if _, err := io.ReadFull(conn, port); err != nil {
+		return "", fmt.Errorf("read socks5 port: %w", err)
+	}
+	if request[1] != socks5CmdConnect {
+		return "", &socks5Error{reply: socks5ReplyCmdNotSupported, err: fmt.Errorf("unsupported socks5 command %d", request[1])}
+	}
+	return net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(port)))), nil
+}
+
+// socks5Reply reports the outcome of the request to the client, a nil error meaning success.
+func socks5Reply(conn net.Conn, err error) {
+	reply := byte(socks5ReplySucceeded)
+	if err != nil {
+		reply = socks5ReplyGeneralFailure
+		var socksErr *socks5Error
+		if errors.As(err, &socksErr) {
+			reply = socksErr.reply
+		}
+	}
+	// The bound address is of no use to clients going through the tunnel, so it is left unspecified
+	_, _ = conn.Write([]byte{socks5Version, reply, 0x00, socks5AddrIPv4, 0, 0, 0, 0, 0, 0})
+}
+
+// httpConnect asks the squid proxy at the other end of the tunnel to connect to the target.
+// The returned connection reads any data buffered while reading the response of the proxy.
+func httpConnect(tun net.Conn, target string) (net.Conn, error) {
+	request := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: target},
+		Host:   target,
+		Header: make(http.Header),
+	}
+	if err := request.Write(tun); err != nil {
+		return nil, fmt.Errorf("send connect request for %s: %w", target, err)
+	}
+
+	reader := bufio.NewReader(tun)
+	response, err := http.ReadResponse(reader, request)
+	if err != nil {
+		return nil, fmt.Errorf("read connect response for %s: %w", target, err)
+	}
+	_ = response.Body.Close()
+
+	switch {
+	case response.StatusCode == http.StatusOK:
+		return &bufferedConn{Conn: tun, reader: reader}, nil
+	case response.StatusCode == http.StatusForbidden:
+		return nil, &socks5Error{reply: socks5ReplyNotAllowed, err: fmt.Errorf("connect to %s denied by proxy: %s", target, response.Status)}
+	default:
+		return nil, &socks5Error{reply: socks5ReplyHostUnreachable, err: fmt.Errorf("connect to %s failed: %s", target, response.Status)}
+	}
+}
+
+// bufferedConn is a connection whose reads start with the data buffered by the reader.
+type bufferedConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func (c *bufferedConn) Read(b []byte) (int, error) {
+	return c.reader.Read(b)
+}
+
+// CloseWrite half-closes the underlying connection when it supports it.
+func (c *bufferedConn) CloseWrite() error {
+	if closer, ok := c.Conn.(interface{ CloseWrite() error }); ok {
+		return closer.CloseWrite()
+	}
+	return c.Conn.Close()
+}
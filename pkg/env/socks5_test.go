@@ -0,0 +1,147 @@
+package env
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/proxy"
+)
+
+// fakeSquidDialer answers HTTP CONNECT requests like the squid proxy on the bastion,
+// echoing the data sent to allowed targets.
+type fakeSquidDialer struct {
+	denied   string
+	requests chan string
+}
+
+func (d *fakeSquidDialer) Dial(ctx context.Context) (net.Conn, error) {
+	client, server := net.Pipe()
+	go func() {
+		defer func() { _ = server.Close() }()
+		reader := bufio.NewReader(server)
+		request, err := http.ReadRequest(reader)
+		if err != nil {
+			return
+		}
+		if d.requests != nil {
+			d.requests <- request.Method + " " + request.Host
+		}
+		if request.Host == d.denied {
+			_, _ = io.WriteString(server, "HTTP/1.1 403 Forbidden\r\n\r\n")
+			return
+		}
+		_, _ = io.WriteString(server, "HTTP/1.1 200 Connection established\r\n\r\n")
+		_, _ = io.Copy(server, reader)
+	}()
+	return client, nil
+}
+
+func startSocksServer(t *testing.T, dialer Dialer) string {
+	l, err := net.Listen("tcp", "localhost:0")
+	assert.NoError(t, err)
+	t.Cleanup(func() { _ = l.Close() })
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go handleSocksClient(context.Background(), dialer, conn)
+		}
+	}()
+	return l.Addr().String()
+}
+
+func TestSocks5Connect(t *testing.T) {
+	squid := &fakeSquidDialer{requests: make(chan string, 1)}
+	client, err := proxy.SOCKS5("tcp", startSocksServer(t, squid), nil, proxy.Direct)
+	assert.NoError(t, err)
+
+	conn, err := client.Dial("tcp", "postgres.db.svc.cluster.local:5432")
+	assert.NoError(t, err)
+	defer func() { _ = conn.Close() }()
+	assert.Equal(t, "CONNECT postgres.db.svc.cluster.local:5432", <-squid.requests)
+
+	message := "hello through socks"
+	_, err = conn.Write([]byte(message))
+	assert.NoError(t, err)
+	buf := make([]byte, len(message))
+	_, err = io.ReadFull(conn, buf)
+	assert.NoError(t, err)
+	assert.Equal(t, message, string(buf))
+}
+
+func TestSocks5ConnectToIP(t *testing.T) {
+	squid := &fakeSquidDialer{requests: make(chan string, 1)}
+	client, err := proxy.SOCKS5("tcp", startSocksServer(t, squid), nil, proxy.Direct)
+	assert.NoError(t, err)
+
+	conn, err := client.Dial("tcp", "10.0.0.1:6379")
+	assert.NoError(t, err)
+	_ = conn.Close()
+	assert.Equal(t, "CONNECT 10.0.0.1:6379", <-squid.requests)
+}
+
+func TestSocks5ConnectDenied(t *testing.T) {
+	squid := &fakeSquidDialer{denied: "forbidden.example.com:443"}
+	client, err := proxy.SOCKS5("tcp", startSocksServer(t, squid), nil, proxy.Direct)
+	assert.NoError(t, err)
+
+	_, err = client.Dial("tcp", "forbidden.example.com:443")
+	assert.ErrorContains(t, err, "connection not allowed by ruleset")
+}
+
+func TestSocks5TunnelFailure(t *testing.T) {
+	client, err := proxy.SOCKS5("tcp", startSocksServer(t, TCPDialer{Address: "localhost:1"}), nil, proxy.Direct)
+	assert.NoError(t, err)
+
+	_, err = client.Dial("tcp", "example.com:443")
+	assert.ErrorContains(t, err, "general SOCKS server failure")
+}
+
+func TestSocks5RejectsUnsupportedRequests(t *testing.T) {
+	address := startSocksServer(t, &fakeSquidDialer{})
+
+	tests := []struct {
+		name     string
+		request  []byte
+		expected []byte
+	}{
+		{
+			name:     "authentication required",
+			request:  []byte{socks5Version, 1, 0x02},
+			expected: []byte{socks5Version, socks5AuthNotAcceptable},
+		},
+		{
+			name:    "bind command",
+			request: []byte{socks5Version, 1, socks5AuthNone, socks5Version, 0x02, 0x00, socks5AddrIPv4, 127, 0, 0, 1, 0, 80},
+			expected: []byte{socks5Version, socks5AuthNone,
+				socks5Version, socks5ReplyCmdNotSupported, 0x00, socks5AddrIPv4, 0, 0, 0, 0, 0, 0},
+		},
+		{
+			name:    "unknown address type",
+			request: []byte{socks5Version, 1, socks5AuthNone, socks5Version, socks5CmdConnect, 0x00, 0x09},
+			expected: []byte{socks5Version, socks5AuthNone,
+				socks5Version, socks5ReplyAddrNotSupported, 0x00, socks5AddrIPv4, 0, 0, 0, 0, 0, 0},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, err := net.Dial("tcp", address)
+			assert.NoError(t, err)
+			defer func() { _ = conn.Close() }()
+
+			_, err = conn.Write(tt.request)
+			assert.NoError(t, err)
+			response, err := io.ReadAll(conn)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, response)
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package env
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/container/apiv1/containerpb"
+	"github.com/coreeng/core-platform/pkg/environment"
+	"github.com/coreeng/corectl/pkg/gcp"
+)
+
+// ClusterStatus is the state of the cluster of an environment.
+// Error is set instead of the state when it couldn't be fetched.
+type ClusterStatus struct {
+	Environment       string           `json:"environment"`
+	Tier              string           `json:"tier"`
+	ProjectId         string           `json:"projectId,omitempty"`
+	Location          string           `json:"location,omitempty"`
+	Status            string           `json:"status,omitempty"`
+	MasterVersion     string           `json:"masterVersion,omitempty"`
+	ReleaseChannel    string           `json:"releaseChannel,omitempty"`
+	MaintenanceWindow string           `json:"maintenanceWindow,omitempty"`
+	NodePools         []NodePoolStatus `json:"nodePools,omitempty"`
+	Error             string           `json:"error,omitempty"`
+}
+
+// NodePoolStatus is the state of a node pool of a cluster.
+type NodePoolStatus struct {
+	Name        string `json:"name"`
+	Status      string `json:"status"`
+	Version     string `json:"version"`
+	Autoscaling bool   `json:"autoscaling"`
+	MinNodes    int32  `json:"minNodes,omitempty"`
+	MaxNodes    int32  `json:"maxNodes,omitempty"`
+}
+
+// GetClusterStatuses fetches the state of the clusters of the environments concurrently,
+// returning them in the order of the environments.
+func GetClusterStatuses(ctx context.Context, client *gcp.Client, environments []environment.Environment) []ClusterStatus {
+	statuses := make([]ClusterStatus, len(environments))
+	var wg sync.WaitGroup
+	for i, env := range environments {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			statuses[i] = getClusterStatus(ctx, client, env)
+		}()
+	}
+	wg.Wait()
+	return statuses
+}
+
+func getClusterStatus(ctx context.Context, client *gcp.Client, env environment.Environment) ClusterStatus {
+	status := ClusterStatus{Environment: env.Environment, Tier: string(env.Tier)}
+	p, ok := env.Platform.(*environment.GCPVendor)
+	if !ok {
+		status.Error = fmt.Sprintf("%s: status is only available for GCP environments", ErrCloudPlatformNotSupported)
+		return status
+	}
+	status.ProjectId = p.ProjectId
+	status.Location = p.Region
+
+	cluster, err := client.GetCluster(ctx, env.Environment, p.Region, p.ProjectId)
+	if err != nil {
+		status.Error = err.Error()
+		return status
+	}
+	status.Status = cluster.GetStatus().String()
+	status.MasterVersion = cluster.GetCurrentMasterVersion()
+	if channel := cluster.GetReleaseChannel(); channel != nil {
+		status.ReleaseChannel = channel.GetChannel().String()
+	}
+	status.MaintenanceWindow = formatMaintenanceWindow(cluster.GetMaintenancePolicy().GetWindow())
+	for _, pool := range cluster.GetNodePools() {
+		autoscaling := pool.GetAutoscaling()
+		status.NodePools = append(status.NodePools, NodePoolStatus{
+			Name:        pool.GetName(),
+			Status:      pool.GetStatus().String(),
+			Version:     pool.GetVersion(),
+			Autoscaling: autoscaling.GetEnabled(),
+			MinNodes:    autoscaling.GetMinNodeCount(),
+			MaxNodes:    autoscaling.GetMaxNodeCount(),
+		})
+	}
+	return status
+}
+
+// formatMaintenanceWindow describes the window in a single line, empty when there is none.
+func formatMaintenanceWindow(window *containerpb.MaintenanceWindow) string {
+	if daily := window.GetDailyMaintenanceWindow(); daily != nil {
+		return fmt.Sprintf("daily at %s UTC", daily.GetStartTime())
+	}
+	if recurring := window.GetRecurringWindow(); recurring != nil {
+		start := recurring.GetWindow().GetStartTime().AsTime()
+		end := recurring.GetWindow().GetEndTime().AsTime()
+		return fmt.Sprintf("%s from %s for %s", recurring.GetRecurrence(), start.Format(time.RFC3339), end.Sub(start))
+	}
+	return ""
+}
+
+// formatNodePool describes the node pool in a single line, e.g. `default 1.30.5 (autoscaling 1-3)`.
+func formatNodePool(pool NodePoolStatus) string {
+	var b strings.Builder
+	b.WriteString(pool.Name + " " + pool.Version)
+	if pool.Autoscaling {
+		fmt.Fprintf(&b, " (autoscaling %d-%d)", pool.MinNodes, pool.MaxNodes)
+	}
+	if pool.Status != containerpb.NodePool_RUNNING.String() {
+		b.WriteString(" " + pool.Status)
+	}
+	return b.String()
+}
@@ -0,0 +1,104 @@
+package env
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/container/apiv1/containerpb"
+	"github.com/coreeng/core-platform/pkg/environment"
+	"github.com/coreeng/corectl/pkg/cmdutil/userio"
+	"github.com/coreeng/corectl/pkg/gcp"
+	gcptest "github.com/coreeng/corectl/pkg/testutil/gcp"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestGetClusterStatuses(t *testing.T) {
+	clusterSvc, err := gcptest.NewClusterMockClient()
+	assert.NoError(t, err)
+	client, err := gcp.NewClient(clusterSvc)
+	assert.NoError(t, err)
+
+	environments := []environment.Environment{
+		{
+			Environment: "predev",
+			Tier:        environment.PreDevEnvironmentTier,
+			Platform:    &environment.GCPVendor{ProjectId: "gcp-predev-1234", Region: "europe-west2"},
+		},
+		{
+			Environment: gcptest.MissingCluster,
+			Tier:        environment.DevEnvironmentTier,
+			Platform:    &environment.GCPVendor{ProjectId: "gcp-dev-1234", Region: "europe-west2"},
+		},
+		{
+			Environment: "production",
+			Tier:        environment.ProdEnvironmentTier,
+			Platform:    &environment.AWSVendor{AccountId: "aws-production-5678", Region: "eu-west-2"},
+		},
+	}
+
+	statuses := GetClusterStatuses(context.Background(), client, environments)
+	assert.Len(t, statuses, 3)
+	assert.Equal(t, ClusterStatus{
+		Environment:       "predev",
+		Tier:              "pre-dev",
+		ProjectId:         "gcp-predev-1234",
+		Location:          "europe-west2",
+		Status:            "RUNNING",
+		MasterVersion:     "1.30.5-gke.1014001",
+		ReleaseChannel:    "REGULAR",
+		MaintenanceWindow: "daily at 03:00 UTC",
+		NodePools: []NodePoolStatus{
+			{Name: "default", Status: "RUNNING", Version: "1.30.5-gke.1014001", Autoscaling: true, MinNodes: 1, MaxNodes: 3},
+			{Name: "system", Status: "RECONCILING", Version: "1.29.8-gke.1211000"},
+		},
+	}, statuses[0])
+	assert.Equal(t, gcptest.MissingCluster, statuses[1].Environment)
+	assert.Contains(t, statuses[1].Error, "NotFound")
+	assert.Empty(t, statuses[1].Status)
+	assert.Equal(t, "production", statuses[2].Environment)
+	assert.Contains(t, statuses[2].Error, ErrCloudPlatformNotSupported.Error())
+}
+
+func TestFormatMaintenanceWindow(t *testing.T) {
+	start := time.Date(2024, 1, 6, 2, 0, 0, 0, time.UTC)
+	recurring := &containerpb.MaintenanceWindow{
+		Policy: &containerpb.MaintenanceWindow_RecurringWindow{
+			RecurringWindow: &containerpb.RecurringTimeWindow{
+				Window: &containerpb.TimeWindow{
+					StartTime: timestamppb.New(start),
+					EndTime:   timestamppb.New(start.Add(6 * time.Hour)),
+				},
+				Recurrence: "FREQ=WEEKLY;BYDAY=SA",
+			},
+		},
+	}
+
+	assert.Equal(t, "FREQ=WEEKLY;BYDAY=SA from 2024-01-06T02:00:00Z for 6h0m0s", formatMaintenanceWindow(recurring))
+	assert.Equal(t, "", formatMaintenanceWindow(nil))
+}
+
+func TestAppendStatus(t *testing.T) {
+	streams := userio.NewIOStreams(os.Stdin, os.Stdout, os.Stderr)
+	table := NewClusterStatusTable(streams)
+	table.AppendStatus(ClusterStatus{
+		Environment:    "predev",
+		Tier:           "pre-dev",
+		Status:         "RUNNING",
+		MasterVersion:  "1.30.5",
+		ReleaseChannel: "REGULAR",
+		NodePools: []NodePoolStatus{
+			{Name: "default", Status: "RUNNING", Version: "1.30.5", Autoscaling: true, MinNodes: 1, MaxNodes: 3},
+			{Name: "system", Status: "RECONCILING", Version: "1.29.8"},
+		},
+	})
+	table.AppendStatus(ClusterStatus{Environment: "dev", Tier: "dev", Error: "not found"})
+
+	compareOutput(t, table.Render(), `
+	NAME    TIER     STATUS            VERSION  CHANNEL  NODEPOOLS                         MAINTENANCE 
+	 predev  pre-dev  RUNNING           1.30.5   REGULAR  default 1.30.5 (autoscaling 1-3)  -           
+	                                                      system 1.29.8 RECONCILING                     
+	 dev     dev      ERROR: not found  -        -        -                                 -`)
+}
@@ -6,21 +6,8 @@ import (
 	"fmt"
 	"math/rand"
 	"os"
-	"strconv"
-	"strings"
-	"syscall"
-
-	"github.com/coreeng/core-platform/pkg/environment"
-	"github.com/coreeng/corectl/pkg/logger"
-	"github.com/shirou/gopsutil/v3/process"
 )
 
-type ProcessDetails struct {
-	Pid  int32
-	Port int32
-}
-
-const PidFileDir = "/tmp/corectl"
 const NoBackgroundEnvVar = "NO_BACKGROUND"
 const PortConnectMin = 30000
 const PortConnectMax = 40000
@@ -41,77 +28,6 @@ func SetBackgroundEnv() string {
 	return fmt.Sprintf("%s=1", NoBackgroundEnvVar)
 }
 
-func WritePidFile(name string, pid int) {
-	if err := os.MkdirAll(PidFileDir, 0755); err != nil {
-		logger.Error().Msgf("Unable to create directory: %v\n", err)
-		return
-	}
-	pidFile := fmt.Sprintf("%s/%s.pid", PidFileDir, name)
-	if err := os.WriteFile(pidFile, []byte(strconv.Itoa(pid)), 0644); err != nil {
-		logger.Error().Msgf("Unable to write file: %v\n", err)
-		return
-	}
-}
-
-func ExistingPidForConnection(name string) int {
-	filename := fmt.Sprintf("%s/%s.pid", PidFileDir, name)
-	content, err := os.ReadFile(filename)
-	if err != nil {
-		return 0
-	}
-
-	pid, err := strconv.Atoi(strings.TrimSpace(string(content)))
-	if err != nil {
-		logger.Error().Msgf("failed to parse pid from file %s: %v", filename, err)
-		return 0
-	}
-	// This is a pain but os.FindProcess always returns a process even if it doesn't exist
-	processes, err := process.Processes()
-	if err != nil {
-		logger.Error().Msgf("Error retrieving processes: %v", err)
-		return 0
-	}
-	for _, proc := range processes {
-		if proc.Pid == int32(pid) {
-			return pid
-		}
-	}
-	return 0
-}
-
-func KillProcess(name string, pid int32, force bool) error {
-	//signal := syscall.SIGTERM
-	signal := syscall.SIGINT
-	if force {
-		signal = syscall.SIGKILL
-	}
-	proc, err := os.FindProcess(int(pid))
-	if err != nil {
-		return fmt.Errorf("failed to find process: %w", err)
-	}
-	err = proc.Signal(signal)
-	if err != nil {
-		return fmt.Errorf("failed to kill process: %w", err)
-	}
-	// Check if pid file exists and remove it
-	filename := fmt.Sprintf("%s/%s.pid", PidFileDir, name)
-	if _, err := os.Stat(filename); err == nil {
-		if err := os.Remove(filename); err != nil {
-			logger.Error().Msgf("failed to remove pid file %s: %v", filename, err)
-		}
-	}
-	return nil
-}
-
-func findEnvironmentByName(name string, environments []environment.Environment) (*environment.Environment, error) {
-	for _, env := range environments {
-		if name == env.Environment {
-			return &env, nil
-		}
-	}
-	return nil, fmt.Errorf("could not find environment: %s", name)
-}
-
 func GenerateConnectPort(name string) int {
 	// Generate a seed based on the environment name for reproducibility
 	hash := sha256.Sum256([]byte(name))
@@ -122,75 +38,3 @@ func GenerateConnectPort(name string) int {
 
 	return r.Intn(PortConnectMax-PortConnectMin) + PortConnectMin
 }
-
-// Get a structure to represent the pids from a directory keyed by filename
-
-func GetProxyPids(availableEnvironments []environment.Environment) (map[string]ProcessDetails, error) {
-	files, err := os.ReadDir(PidFileDir)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read directory: %w", err)
-	}
-
-	pidMap := make(map[string]ProcessDetails)
-	processes, err := process.Processes()
-	if err != nil {
-		logger.Error().Msgf("unable to read processes: %v", err)
-		return pidMap, err
-	}
-	for _, file := range files {
-		if file.IsDir() {
-			continue
-		}
-		// string the .pid extension from the filename
-		filename := strings.TrimSuffix(file.Name(), ".pid")
-		// check if the filename is an environment
-		if _, err := findEnvironmentByName(filename, availableEnvironments); err != nil {
-			// if the filename is not an environment, skip it
-			continue
-		}
-
-		content, err := os.ReadFile(fmt.Sprintf("%s/%s", PidFileDir, file.Name()))
-		if err != nil {
-			logger.Error().Msgf("failed to read file %s: %v", file.Name(), err)
-			continue
-		}
-
-		pid, err := strconv.Atoi(strings.TrimSpace(string(content)))
-		if err != nil {
-			logger.Error().Msgf("failed to parse pid from file %s: %v", file.Name(), err)
-			continue
-		}
-		exists := false
-		// Set value to true is the pid exists in the list of processes
-		for _, proc := range processes {
-			if proc.Pid == int32(pid) {
-				exists = true
-				connections, err := proc.Connections()
-				_ = connections
-				if err != nil {
-					logger.Error().Msgf("failed to get connections for pid %d: %v", pid, err)
-					continue
-				}
-				if len(connections) == 0 {
-					logger.Error().Msgf("no connections found for pid %d", pid)
-					continue
-				}
-				pidMap[filename] = ProcessDetails{
-					Pid:  int32(pid),
-					Port: int32(connections[0].Laddr.Port),
-				}
-
-			}
-		}
-		// If the pid does not exist, remove the file
-		if !exists {
-			logger.Error().Msgf("removing pid file %s for pid %d", file.Name(), pid)
-			if err := os.Remove(fmt.Sprintf("%s/%s", PidFileDir, file.Name())); err != nil {
-				logger.Error().Msgf("failed to remove pid file %s: %v", file.Name(), err)
-			}
-			continue
-		}
-	}
-
-	return pidMap, nil
-}
@@ -5,7 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"github.com/coreeng/corectl/pkg/command"
-	"strings"
+	"io"
 
 	"github.com/coreeng/core-platform/pkg/environment"
 	"github.com/coreeng/corectl/pkg/gcp"
@@ -16,39 +16,76 @@ var (
 	ErrInvalidEnvironment        = errors.New("environment is not valid")
 )
 
-// Validate checks if the required tools and configurations for the environment are installed and set up correctly.
-func Validate(ctx context.Context, env *environment.Environment, cmd command.Commander, client *gcp.Client) error {
+// Validate checks if the required tools and configurations for the environment are installed and set up correctly,
+// that the caller has the IAM permissions needed to connect, and that the cluster and the bastion of the environment exist.
+func Validate(ctx context.Context, env *environment.Environment, bastion Bastion, cmd command.Commander, client *gcp.Client) error {
 	if env == nil {
 		return ErrInvalidEnvironment
 	}
 
-	if err := command.DepsInstalled(cmd, "gcloud", "kubectl"); err != nil {
-		return err
-	}
-
 	if err := checkPlatformSupported(env); err != nil {
 		return err
 	}
 
-	if err := checkClusterExists(ctx, client, env); err != nil {
-		return err
+	switch p := env.Platform.(type) {
+	case *environment.GCPVendor:
+		checks, err := CheckPermissions(ctx, client, env)
+		if err != nil {
+			return err
+		}
+		if err := missingPermissions(p.ProjectId, checks); err != nil {
+			return err
+		}
+		if err := checkClusterExists(ctx, client, env.Environment, p); err != nil {
+			return err
+		}
+		if err := checkBastionExists(ctx, client, bastion, p); err != nil {
+			return err
+		}
+	case *environment.AWSVendor:
+		if err := command.DepsInstalled(cmd, "aws"); err != nil {
+			return err
+		}
+		if err := checkEKSClusterExists(cmd, env.Environment, p); err != nil {
+			return err
+		}
+		provider := &awsProvider{cluster: env.Environment, vendor: p, bastion: bastion, exec: cmd}
+		if _, err := provider.bastionInstanceId(); err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
 // checkClusterExists checks if the cluster for the given environment is present in gcp.
-func checkClusterExists(ctx context.Context, c *gcp.Client, env *environment.Environment) error {
-	if _, err := c.GetCluster(ctx, env.Environment, env.Platform.(*environment.GCPVendor).Region, env.Platform.(*environment.GCPVendor).ProjectId); err != nil {
+func checkClusterExists(ctx context.Context, c *gcp.Client, cluster string, p *environment.GCPVendor) error {
+	if _, err := c.GetCluster(ctx, cluster, p.Region, p.ProjectId); err != nil {
 		return err
 	}
 
 	return nil
 }
 
-func checkPlatformSupported(env *environment.Environment) error {
-	if env.Platform.Type() != environment.GCPVendorType {
-		return fmt.Errorf("%s %w", strings.ToUpper(string(env.Platform.Type())), ErrCloudPlatformNotSupported)
+// checkEKSClusterExists checks if the cluster for the given environment is present in aws.
+func checkEKSClusterExists(cmd command.Commander, cluster string, p *environment.AWSVendor) error {
+	if _, err := cmd.Execute("aws", command.WithArgs("eks", "describe-cluster", "--region", p.Region, "--name", cluster), command.WithOverrideStdout(io.Discard)); err != nil {
+		return fmt.Errorf("get aws cluster %q: %w", cluster, err)
+	}
+
+	return nil
+}
+
+// checkBastionExists checks if the bastion instance for the given environment is present in its gcp zone.
+func checkBastionExists(ctx context.Context, c *gcp.Client, bastion Bastion, p *environment.GCPVendor) error {
+	if _, err := c.GetInstance(ctx, bastion.Instance, bastion.Zone, p.ProjectId); err != nil {
+		return fmt.Errorf("bastion instance %q not found in zone %s: %w", bastion.Instance, bastion.Zone, err)
 	}
+
 	return nil
 }
+
+func checkPlatformSupported(env *environment.Environment) error {
+	_, err := NewProvider(env, Bastion{}, nil, nil)
+	return err
+}
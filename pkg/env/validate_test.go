@@ -2,7 +2,6 @@ package env
 
 import (
 	"context"
-	"errors"
 	"fmt"
 	"github.com/coreeng/corectl/pkg/command"
 	"os"
@@ -37,24 +36,40 @@ func TestValidate(t *testing.T) {
 				Environment: "production",
 				Platform: &environment.AWSVendor{
 					AccountId: "aws-production-5678",
+					Region:    "eu-west-2",
 				},
 			},
-			err: errors.New("AWS cloud platform is not supported"),
+			err: nil,
+		},
+		{
+			name: "Missing environment",
+			env:  nil,
+			err:  ErrInvalidEnvironment,
 		},
 	}
 
 	clusterSvc, err := gcptest.NewClusterMockClient()
 	assert.NoError(t, err)
 
+	computeSvc, err := gcptest.NewComputeMockService("projects/gcp-predev-1234/zones/europe-west2-a/instances/predev-bastion")
+	assert.NoError(t, err)
+
+	resourceManagerSvc, err := gcptest.NewResourceManagerMockService(requiredPermissions()...)
+	assert.NoError(t, err)
+
 	ctx := context.Background()
-	client, err := gcp.NewClient(clusterSvc)
+	client, err := gcp.NewClient(clusterSvc, gcp.WithComputeService(computeSvc), gcp.WithResourceManagerService(resourceManagerSvc))
 	assert.NoError(t, err)
 
 	mockCmd := &mockCommand{}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			err := Validate(ctx, tt.env, mockCmd, client)
+			var bastion Bastion
+			if tt.env != nil {
+				bastion = ResolveBastion(tt.env, Bastion{})
+			}
+			err := Validate(ctx, tt.env, bastion, mockCmd, client)
 			if tt.err != nil {
 				assert.Error(t, err)
 				assert.Equal(t, tt.err.Error(), err.Error())
@@ -63,6 +78,76 @@ func TestValidate(t *testing.T) {
 	}
 }
 
+func TestValidateReportsMissingPermissions(t *testing.T) {
+	env := &environment.Environment{
+		Environment: "predev",
+		Platform:    &environment.GCPVendor{ProjectId: "gcp-predev-1234", Region: "europe-west2"},
+	}
+	clusterSvc, err := gcptest.NewClusterMockClient()
+	assert.NoError(t, err)
+	resourceManagerSvc, err := gcptest.NewResourceManagerMockService("container.clusters.get", "compute.instances.get")
+	assert.NoError(t, err)
+	client, err := gcp.NewClient(clusterSvc, gcp.WithResourceManagerService(resourceManagerSvc))
+	assert.NoError(t, err)
+
+	err = Validate(context.Background(), env, ResolveBastion(env, Bastion{}), &mockCommand{}, client)
+	assert.Equal(t, MissingPermissionsError{
+		Project: "gcp-predev-1234",
+		Missing: []RequiredPermission{GCPRequiredPermissions[2]},
+	}, err)
+	assert.EqualError(t, err, `missing IAM permissions on project gcp-predev-1234:
+  iap.tunnelInstances.accessViaIAP, granted by roles/iap.tunnelResourceAccessor, to open an IAP tunnel to the bastion instance`)
+}
+
+func TestCheckPermissions(t *testing.T) {
+	resourceManagerSvc, err := gcptest.NewResourceManagerMockService("compute.instances.get")
+	assert.NoError(t, err)
+	client, err := gcp.NewClient(nil, gcp.WithResourceManagerService(resourceManagerSvc))
+	assert.NoError(t, err)
+	ctx := context.Background()
+
+	checks, err := CheckPermissions(ctx, client, &environment.Environment{
+		Platform: &environment.GCPVendor{ProjectId: "gcp-predev-1234"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []PermissionCheck{
+		{RequiredPermission: GCPRequiredPermissions[0], Granted: false},
+		{RequiredPermission: GCPRequiredPermissions[1], Granted: true},
+		{RequiredPermission: GCPRequiredPermissions[2], Granted: false},
+	}, checks)
+
+	checks, err = CheckPermissions(ctx, client, &environment.Environment{
+		Platform: &environment.AWSVendor{AccountId: "aws-production-5678"},
+	})
+	assert.NoError(t, err)
+	assert.Empty(t, checks)
+}
+
+func requiredPermissions() []string {
+	var permissions []string
+	for _, p := range GCPRequiredPermissions {
+		permissions = append(permissions, p.Permission)
+	}
+	return permissions
+}
+
+func TestCheckBastionExists(t *testing.T) {
+	vendor := &environment.GCPVendor{ProjectId: "gcp-us-1234", Region: "us-central1"}
+	bastion := Bastion{Instance: "us-dev-bastion", Zone: "us-central1-a"}
+
+	computeSvc, err := gcptest.NewComputeMockService("projects/gcp-us-1234/zones/us-central1-a/instances/us-dev-bastion")
+	assert.NoError(t, err)
+	client, err := gcp.NewClient(nil, gcp.WithComputeService(computeSvc))
+	assert.NoError(t, err)
+
+	ctx := context.Background()
+	assert.NoError(t, checkBastionExists(ctx, client, bastion, vendor))
+
+	bastion.Zone = "us-central1-b"
+	err = checkBastionExists(ctx, client, bastion, vendor)
+	assert.ErrorContains(t, err, `bastion instance "us-dev-bastion" not found in zone us-central1-b`)
+}
+
 type mockCommand struct {
 }
 
@@ -6,10 +6,23 @@ import (
 
 	container "cloud.google.com/go/container/apiv1"
 	"cloud.google.com/go/container/apiv1/containerpb"
+	"google.golang.org/api/cloudresourcemanager/v1"
+	"google.golang.org/api/compute/v1"
 )
 
 type Client struct {
-	clusterSvc *container.ClusterManagerClient
+	clusterSvc         *container.ClusterManagerClient
+	computeSvc         *compute.Service
+	resourceManagerSvc *cloudresourcemanager.Service
+}
+
+type ClientOption func(*Client)
+
+// WithComputeService lets the client interact with GCE instances
+func WithComputeService(computeSvc *compute.Service) ClientOption {
+	return func(c *Client) {
+		c.computeSvc = computeSvc
+	}
 }
 
 type GCloudError struct {
@@ -25,8 +38,12 @@ func newGCloudError(format string, args ...any) error {
 }
 
 // NewClient will return a client that has permissions to interact with GCP services
-func NewClient(clusterManager *container.ClusterManagerClient) (*Client, error) {
-	return &Client{clusterSvc: clusterManager}, nil
+func NewClient(clusterManager *container.ClusterManagerClient, opts ...ClientOption) (*Client, error) {
+	c := &Client{clusterSvc: clusterManager}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c, nil
 }
 
 // NewClusterClient creates a client that can be used to interact with GKE clusters
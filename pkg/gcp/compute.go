@@ -0,0 +1,35 @@
+package gcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"google.golang.org/api/compute/v1"
+)
+
+var ErrComputeNotConfigured = errors.New("compute service is not configured")
+
+// NewComputeService creates a service that can be used to interact with GCE instances
+func NewComputeService(ctx context.Context) (*compute.Service, error) {
+	s, err := compute.NewService(ctx)
+	if err != nil {
+		return nil, newGCloudError("create google compute service: %s", err)
+	}
+	return s, nil
+}
+
+// GetInstance will return GCE instance details
+func (c *Client) GetInstance(ctx context.Context, instance, zone, project string) (*compute.Instance, error) {
+	if c.computeSvc == nil {
+		return nil, ErrComputeNotConfigured
+	}
+
+	query := fmt.Sprintf("projects/%s/zones/%s/instances/%s", project, zone, instance)
+	resp, err := c.computeSvc.Instances.Get(project, zone, instance).Context(ctx).Do()
+	if err != nil {
+		return nil, newGCloudError("get GCP instance %q: %s", query, err)
+	}
+
+	return resp, nil
+}
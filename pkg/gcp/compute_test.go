@@ -0,0 +1,31 @@
+package gcp
+
+import (
+	"context"
+	"testing"
+
+	gcptest "github.com/coreeng/corectl/pkg/testutil/gcp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetInstance(t *testing.T) {
+	computeSvc, err := gcptest.NewComputeMockService("projects/gcp-predev-1234/zones/europe-west2-a/instances/predev-bastion")
+	assert.NoError(t, err)
+	c, err := NewClient(nil, WithComputeService(computeSvc))
+	assert.NoError(t, err)
+
+	instance, err := c.GetInstance(context.Background(), "predev-bastion", "europe-west2-a", "gcp-predev-1234")
+	assert.NoError(t, err)
+	assert.Equal(t, "predev-bastion", instance.Name)
+
+	_, err = c.GetInstance(context.Background(), "predev-bastion", "europe-west2-b", "gcp-predev-1234")
+	assert.ErrorContains(t, err, `get GCP instance "projects/gcp-predev-1234/zones/europe-west2-b/instances/predev-bastion"`)
+}
+
+func TestGetInstanceWithoutComputeService(t *testing.T) {
+	c, err := NewClient(nil)
+	assert.NoError(t, err)
+
+	_, err = c.GetInstance(context.Background(), "predev-bastion", "europe-west2-a", "gcp-predev-1234")
+	assert.ErrorIs(t, err, ErrComputeNotConfigured)
+}
@@ -0,0 +1,49 @@
+package gcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"google.golang.org/api/cloudresourcemanager/v1"
+	"google.golang.org/api/googleapi"
+)
+
+var ErrResourceManagerNotConfigured = errors.New("resource manager service is not configured")
+
+// WithResourceManagerService lets the client check the IAM permissions of the caller on projects
+func WithResourceManagerService(resourceManagerSvc *cloudresourcemanager.Service) ClientOption {
+	return func(c *Client) {
+		c.resourceManagerSvc = resourceManagerSvc
+	}
+}
+
+// NewResourceManagerService creates a service that can be used to check IAM permissions on GCP projects
+func NewResourceManagerService(ctx context.Context) (*cloudresourcemanager.Service, error) {
+	s, err := cloudresourcemanager.NewService(ctx)
+	if err != nil {
+		return nil, newGCloudError("create google resource manager service: %s", err)
+	}
+	return s, nil
+}
+
+// TestPermissions will return the permissions the caller has on the project, out of the given ones
+func (c *Client) TestPermissions(ctx context.Context, project string, permissions []string) ([]string, error) {
+	if c.resourceManagerSvc == nil {
+		return nil, ErrResourceManagerNotConfigured
+	}
+
+	req := &cloudresourcemanager.TestIamPermissionsRequest{Permissions: permissions}
+	resp, err := c.resourceManagerSvc.Projects.TestIamPermissions(project, req).Context(ctx).Do()
+	if err != nil {
+		// The permissions of a project the caller has no access to can't be tested, credentials aren't the issue
+		var apiErr *googleapi.Error
+		if errors.As(err, &apiErr) && apiErr.Code == http.StatusForbidden {
+			return nil, fmt.Errorf("no access to GCP project %q, check it exists and a role on it is granted to the caller: %w", project, err)
+		}
+		return nil, fmt.Errorf("test IAM permissions on GCP project %q: %w", project, err)
+	}
+
+	return resp.Permissions, nil
+}
@@ -0,0 +1,42 @@
+package gcp
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	gcptest "github.com/coreeng/corectl/pkg/testutil/gcp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTestPermissions(t *testing.T) {
+	resourceManagerSvc, err := gcptest.NewResourceManagerMockService("container.clusters.get")
+	assert.NoError(t, err)
+	c, err := NewClient(nil, WithResourceManagerService(resourceManagerSvc))
+	assert.NoError(t, err)
+
+	granted, err := c.TestPermissions(context.Background(), "gcp-predev-1234", []string{"container.clusters.get", "compute.instances.get"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"container.clusters.get"}, granted)
+}
+
+func TestTestPermissionsWithoutResourceManagerService(t *testing.T) {
+	c, err := NewClient(nil)
+	assert.NoError(t, err)
+
+	_, err = c.TestPermissions(context.Background(), "gcp-predev-1234", []string{"container.clusters.get"})
+	assert.ErrorIs(t, err, ErrResourceManagerNotConfigured)
+}
+
+func TestTestPermissionsWithoutProjectAccess(t *testing.T) {
+	resourceManagerSvc, err := gcptest.NewResourceManagerForbiddenMockService()
+	assert.NoError(t, err)
+	c, err := NewClient(nil, WithResourceManagerService(resourceManagerSvc))
+	assert.NoError(t, err)
+
+	_, err = c.TestPermissions(context.Background(), "gcp-predev-1234", []string{"container.clusters.get"})
+	assert.ErrorContains(t, err, `no access to GCP project "gcp-predev-1234"`)
+	assert.NotContains(t, err.Error(), "application-default login")
+	var gcloudErr GCloudError
+	assert.False(t, errors.As(err, &gcloudErr))
+}
@@ -276,6 +276,23 @@ func (localRepo *LocalRepository) AddFiles(paths ...string) error {
 	return nil
 }
 
+// RemoveFiles removes the files from the worktree and the index, like git rm.
+func (localRepo *LocalRepository) RemoveFiles(paths ...string) error {
+	for _, p := range paths {
+		logger.Debug().With(
+			zap.String("repo", localRepo.Path()),
+			zap.Bool("dry_run", localRepo.DryRun),
+			zap.String("path", p)).
+			Msg("git: removing path")
+		if !localRepo.DryRun {
+			if _, err := localRepo.worktree.Remove(p); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
 func (localRepo *LocalRepository) IsLocalChangesPresent() (bool, error) {
 	status, err := localRepo.worktree.Status()
 	if err != nil {
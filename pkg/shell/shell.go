@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"os"
 	"os/exec"
+	"os/signal"
+	"syscall"
 
 	"github.com/coreeng/corectl/pkg/logger"
 )
@@ -31,3 +33,31 @@ func RunCommand(dir string, name string, args ...string) (string, string, error)
 	}
 	return stdout.String(), stderr.String(), err
 }
+
+// RunAttached runs the command, set up with the streams it is attached to, until it exits.
+// The terminal delivers interrupts to the command itself, so while it runs corectl ignores them
+// and only forwards the signals meant for corectl alone, like termination and hangup.
+func RunAttached(cmd *exec.Cmd) error {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGQUIT, syscall.SIGTERM, syscall.SIGHUP)
+	defer signal.Stop(signals)
+
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			select {
+			case sig := <-signals:
+				if sig == syscall.SIGTERM || sig == syscall.SIGHUP {
+					_ = cmd.Process.Signal(sig)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+	return cmd.Wait()
+}
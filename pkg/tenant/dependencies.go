@@ -0,0 +1,27 @@
+package tenant
+
+import (
+	coretnt "github.com/coreeng/core-platform/pkg/tenant"
+)
+
+// DeliveryUnitsOf returns the delivery units owned by the org unit.
+func DeliveryUnitsOf(tenants []coretnt.Tenant, orgUnit string) []*coretnt.Tenant {
+	var deliveryUnits []*coretnt.Tenant
+	for i := range tenants {
+		if tenants[i].Kind == "DeliveryUnit" && tenants[i].Owner == orgUnit {
+			deliveryUnits = append(deliveryUnits, &tenants[i])
+		}
+	}
+	return deliveryUnits
+}
+
+// Repos returns the repositories of the tenants, which are left without a tenant once they are deleted.
+func Repos(tenants []*coretnt.Tenant) []string {
+	var repos []string
+	for _, t := range tenants {
+		if t.Repo != "" {
+			repos = append(repos, t.Repo)
+		}
+	}
+	return repos
+}
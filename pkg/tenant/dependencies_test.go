@@ -0,0 +1,33 @@
+package tenant
+
+import (
+	coretnt "github.com/coreeng/core-platform/pkg/tenant"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("tenant dependencies", func() {
+	tenants := []coretnt.Tenant{
+		{Name: "ou1", Kind: "OrgUnit"},
+		{Name: "ou2", Kind: "OrgUnit"},
+		{Name: "du1", Kind: "DeliveryUnit", Owner: "ou1", Repo: "https://github.com/org/du1"},
+		{Name: "du2", Kind: "DeliveryUnit", Owner: "ou1"},
+		{Name: "du3", Kind: "DeliveryUnit", Owner: "ou2", Repo: "https://github.com/org/du3"},
+	}
+
+	It("finds the delivery units of an org unit", func() {
+		deliveryUnits := DeliveryUnitsOf(tenants, "ou1")
+		Expect(deliveryUnits).To(HaveLen(2))
+		Expect(deliveryUnits[0].Name).To(Equal("du1"))
+		Expect(deliveryUnits[1].Name).To(Equal("du2"))
+		Expect(deliveryUnits[0]).To(BeIdenticalTo(&tenants[2]))
+	})
+
+	It("finds no delivery unit for a delivery unit", func() {
+		Expect(DeliveryUnitsOf(tenants, "du1")).To(BeEmpty())
+	})
+
+	It("lists the repositories of the tenants", func() {
+		Expect(Repos(DeliveryUnitsOf(tenants, "ou1"))).To(Equal([]string{"https://github.com/org/du1"}))
+	})
+})
@@ -0,0 +1,52 @@
+package tenant
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/coreeng/core-platform/pkg/tenant"
+	"github.com/pmezard/go-difflib/difflib"
+	"gopkg.in/yaml.v3"
+)
+
+// Diff returns the unified diff of the YAML definitions of the tenant before and after a change,
+// it is empty when the definitions are the same.
+func Diff(before *tenant.Tenant, after *tenant.Tenant) (string, error) {
+	beforeDefinition, err := yaml.Marshal(before)
+	if err != nil {
+		return "", err
+	}
+	afterDefinition, err := yaml.Marshal(after)
+	if err != nil {
+		return "", err
+	}
+	beforePath, err := FilePath(before)
+	if err != nil {
+		return "", err
+	}
+	afterPath, err := FilePath(after)
+	if err != nil {
+		return "", err
+	}
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(string(beforeDefinition)),
+		B:        difflib.SplitLines(string(afterDefinition)),
+		FromFile: "a/" + beforePath,
+		ToFile:   "b/" + afterPath,
+		Context:  3,
+	})
+}
+
+// FilePath returns the path of the definition of the tenant relative to the cplatform repository,
+// following the layout of core-platform: tenants/<name>.ou.yaml and tenants/<owner>/<name>.du.yaml.
+func FilePath(t *tenant.Tenant) (string, error) {
+	switch t.Kind {
+	case "OrgUnit":
+		return filepath.Join("tenants", t.Name+".ou.yaml"), nil
+	case "DeliveryUnit":
+		// DeliveryUnit always has an owner (required by ADR).
+		return filepath.Join("tenants", t.Owner, t.Name+".du.yaml"), nil
+	default:
+		return "", fmt.Errorf("unknown tenant kind: %s", t.Kind)
+	}
+}
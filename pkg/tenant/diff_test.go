@@ -0,0 +1,42 @@
+package tenant
+
+import (
+	coretnt "github.com/coreeng/core-platform/pkg/tenant"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("tenant diff", func() {
+	before := coretnt.Tenant{
+		Name:         "du",
+		Kind:         "DeliveryUnit",
+		Owner:        "ou",
+		Description:  "Delivery unit",
+		ContactEmail: "old@company.com",
+		Environments: []string{"dev"},
+	}
+
+	It("is empty when the tenant is unchanged", func() {
+		after := before
+		diff, err := Diff(&before, &after)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(diff).To(BeEmpty())
+	})
+
+	It("shows the changed lines of the definition", func() {
+		after := before
+		after.ContactEmail = "new@company.com"
+		after.Environments = []string{"dev", "prod"}
+		diff, err := Diff(&before, &after)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(diff).To(ContainSubstring("--- a/tenants/ou/du.du.yaml\n+++ b/tenants/ou/du.du.yaml\n"))
+		Expect(diff).To(ContainSubstring("-contactEmail: old@company.com\n+contactEmail: new@company.com\n"))
+		Expect(diff).To(ContainSubstring("+    - prod\n"))
+	})
+
+	It("fails for an unknown kind", func() {
+		unknown := coretnt.Tenant{Name: "x", Kind: "Unknown"}
+		_, err := Diff(&unknown, &unknown)
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -1,18 +1,213 @@
 package tenant
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
 	"github.com/coreeng/core-platform/pkg/tenant"
 	"github.com/coreeng/corectl/pkg/cmdutil/userio"
 	"github.com/jedib0t/go-pretty/v6/table"
+	"gopkg.in/yaml.v3"
 )
 
+// Column is a field of the tenants listed. Its name is the stable name of the field in structured output,
+// the same as in the tenant definition.
+type Column struct {
+	Name   string
+	Header string
+	value  func(t tenant.Tenant) any
+}
+
+// Columns are the columns tenants can be listed with.
+var Columns = []Column{
+	{Name: "name", Header: "Name", value: func(t tenant.Tenant) any { return t.Name }},
+	{Name: "kind", Header: "Kind", value: func(t tenant.Tenant) any { return t.Kind }},
+	{Name: "owner", Header: "Owner", value: func(t tenant.Tenant) any { return t.Owner }},
+	{Name: "type", Header: "Type", value: func(t tenant.Tenant) any { return t.Type }},
+	{Name: "prefix", Header: "Prefix", value: func(t tenant.Tenant) any { return t.Prefix }},
+	{Name: "repo", Header: "Repo", value: func(t tenant.Tenant) any { return t.Repo }},
+	{Name: "contactEmail", Header: "Contact Email", value: func(t tenant.Tenant) any { return t.ContactEmail }},
+	{Name: "description", Header: "Description", value: func(t tenant.Tenant) any { return t.Description }},
+	{Name: "costCentre", Header: "Cost Centre", value: func(t tenant.Tenant) any { return t.CostCentre }},
+	{Name: "environments", Header: "Environments", value: func(t tenant.Tenant) any { return nonNil(t.Environments) }},
+	{Name: "adminGroup", Header: "Admin Group", value: func(t tenant.Tenant) any { return t.AdminGroup }},
+	{Name: "readonlyGroup", Header: "Readonly Group", value: func(t tenant.Tenant) any { return t.ReadOnlyGroup }},
+}
+
+// DefaultColumns are the names of the columns tenants are listed with in a table.
+var DefaultColumns = []string{"name", "kind", "owner", "type", "prefix", "repo", "contactEmail"}
+
+// ColumnNames returns the names of all the columns.
+func ColumnNames() []string {
+	names := make([]string, len(Columns))
+	for i, c := range Columns {
+		names[i] = c.Name
+	}
+	return names
+}
+
+// SelectColumns returns the columns with the names, in order.
+func SelectColumns(names []string) ([]Column, error) {
+	columns := make([]Column, 0, len(names))
+	for _, name := range names {
+		i := slices.IndexFunc(Columns, func(c Column) bool { return c.Name == name })
+		if i < 0 {
+			return nil, fmt.Errorf("unknown column %q, must be one of: %s", name, strings.Join(ColumnNames(), ", "))
+		}
+		columns = append(columns, Columns[i])
+	}
+	return columns, nil
+}
+
+// Text returns the value of the column for the tenant as text, lists being comma separated.
+func (c Column) Text(t tenant.Tenant) string {
+	switch value := c.value(t).(type) {
+	case []string:
+		return strings.Join(value, ",")
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+// Field is the value of a column, named after it.
+type Field struct {
+	Name  string
+	Value any
+}
+
+// Fields are values of columns, encoded as a JSON or YAML object keeping their order.
+type Fields []Field
+
+// Record returns the values of the columns for the tenant, in the order of the columns.
+func Record(t tenant.Tenant, columns []Column) Fields {
+	record := make(Fields, len(columns))
+	for i, c := range columns {
+		record[i] = Field{Name: c.Name, Value: c.value(t)}
+	}
+	return record
+}
+
+func (f Fields) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, field := range f {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		name, err := json.Marshal(field.Name)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(field.Value)
+		if err != nil {
+			return nil, fmt.Errorf("encode %s: %w", field.Name, err)
+		}
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+func (f Fields) MarshalYAML() (any, error) {
+	node := &yaml.Node{Kind: yaml.MappingNode}
+	for _, field := range f {
+		value := &yaml.Node{}
+		if err := value.Encode(field.Value); err != nil {
+			return nil, fmt.Errorf("encode %s: %w", field.Name, err)
+		}
+		node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: field.Name}, value)
+	}
+	return node, nil
+}
+
+// Filter selects the tenants listed, its empty fields select all the tenants.
+type Filter struct {
+	Kind        string
+	Owner       string
+	Type        string
+	Environment string
+	// RepoMissing selects the delivery units without a repository
+	RepoMissing bool
+	// ContactEmail is a glob pattern the contact email has to match, ignoring case
+	ContactEmail string
+}
+
+// Validate checks that the contact email pattern is a valid glob.
+func (f Filter) Validate() error {
+	if _, err := path.Match(strings.ToLower(f.ContactEmail), ""); err != nil {
+		return fmt.Errorf("invalid contact email pattern %q: %w", f.ContactEmail, err)
+	}
+	return nil
+}
+
+// Matches reports whether the tenant is selected by the filter.
+func (f Filter) Matches(t tenant.Tenant) bool {
+	if f.Kind != "" && !strings.EqualFold(t.Kind, f.Kind) {
+		return false
+	}
+	if f.Owner != "" && t.Owner != f.Owner {
+		return false
+	}
+	if f.Type != "" && t.Type != f.Type {
+		return false
+	}
+	if f.Environment != "" && !slices.Contains(t.Environments, f.Environment) {
+		return false
+	}
+	if f.RepoMissing && (t.Kind != "DeliveryUnit" || t.Repo != "") {
+		return false
+	}
+	if f.ContactEmail != "" {
+		matched, err := path.Match(strings.ToLower(f.ContactEmail), strings.ToLower(t.ContactEmail))
+		if err != nil || !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// Apply returns the tenants selected by the filter.
+func (f Filter) Apply(tenants []tenant.Tenant) []tenant.Tenant {
+	var selected []tenant.Tenant
+	for _, t := range tenants {
+		if f.Matches(t) {
+			selected = append(selected, t)
+		}
+	}
+	return selected
+}
+
+func nonNil(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
+
 type Table struct {
-	table table.Writer
+	table   table.Writer
+	columns []Column
 }
 
 func NewTable(streams userio.IOStreams) Table {
+	columns, _ := SelectColumns(DefaultColumns)
+	return NewTableWithColumns(streams, columns)
+}
+
+// NewTableWithColumns returns a table of tenants with the columns.
+func NewTableWithColumns(streams userio.IOStreams, columns []Column) Table {
 	t := table.NewWriter()
-	t.AppendHeader(table.Row{"Name", "Kind", "Owner", "Type", "Prefix", "Repo", "Contact Email"})
+	header := make(table.Row, len(columns))
+	for i, c := range columns {
+		header[i] = c.Header
+	}
+	t.AppendHeader(header)
 	t.Style().Options.DrawBorder = false
 	t.Style().Options.SeparateColumns = false
 	t.Style().Options.SeparateFooter = false
@@ -20,11 +215,15 @@ func NewTable(streams userio.IOStreams) Table {
 	t.Style().Options.SeparateRows = false
 	t.SetOutputMirror(streams.GetOutput())
 
-	return Table{table: t}
+	return Table{table: t, columns: columns}
 }
 
 func (t Table) AppendRow(tnnt tenant.Tenant) {
-	t.table.AppendRows([]table.Row{{tnnt.Name, tnnt.Kind, tnnt.Owner, tnnt.Type, tnnt.Prefix, tnnt.Repo, tnnt.ContactEmail}})
+	row := make(table.Row, len(t.columns))
+	for i, c := range t.columns {
+		row[i] = c.Text(tnnt)
+	}
+	t.table.AppendRows([]table.Row{row})
 }
 
 func (t Table) Render() string {
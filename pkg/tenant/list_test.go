@@ -46,3 +46,63 @@ var _ = Describe("tenant table", func() {
 		}, []string{"tenant1", "DeliveryUnit", "parent1", "application", "https://github.com/org/repo1", "tenant1@company.com", "tenant2", "parent2", "infrastructure", "area/subarea", "tenant2@company.com"}),
 	)
 })
+
+var _ = Describe("tenant columns", func() {
+	t := coretnt.Tenant{Name: "tenant1", Kind: "DeliveryUnit", Environments: []string{"dev", "prod"}}
+
+	It("selects the columns in order", func() {
+		columns, err := SelectColumns([]string{"environments", "name"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(columns[0].Text(t)).To(Equal("dev,prod"))
+		Expect(columns[1].Text(t)).To(Equal("tenant1"))
+		Expect(Record(t, columns)).To(Equal(Fields{{Name: "environments", Value: []string{"dev", "prod"}}, {Name: "name", Value: "tenant1"}}))
+	})
+
+	It("rejects an unknown column", func() {
+		_, err := SelectColumns([]string{"name", "unknown"})
+		Expect(err).To(MatchError(ContainSubstring(`unknown column "unknown"`)))
+	})
+
+	It("renders the selected columns only", func() {
+		var stdin, stdout, stderr bytes.Buffer
+		columns, err := SelectColumns([]string{"name", "environments"})
+		Expect(err).NotTo(HaveOccurred())
+		table := NewTableWithColumns(userio.NewIOStreams(&stdin, &stdout, &stderr), columns)
+		table.AppendRow(t)
+		result := table.Render()
+		Expect(result).To(ContainSubstring("ENVIRONMENTS"))
+		Expect(result).To(ContainSubstring("dev,prod"))
+		Expect(result).NotTo(ContainSubstring("KIND"))
+	})
+})
+
+var _ = Describe("tenant filter", func() {
+	tenants := []coretnt.Tenant{
+		{Name: "ou1", Kind: "OrgUnit", ContactEmail: "ou1@company.com", Environments: []string{"dev", "prod"}},
+		{Name: "du1", Kind: "DeliveryUnit", Owner: "ou1", Type: "application", Repo: "https://github.com/org/du1", ContactEmail: "Team@Company.com", Environments: []string{"dev"}},
+		{Name: "du2", Kind: "DeliveryUnit", Owner: "ou1", Type: "infrastructure", ContactEmail: "team@other.com", Environments: []string{"prod"}},
+	}
+
+	DescribeTable("selects the tenants",
+		func(filter Filter, expected []string) {
+			var names []string
+			for _, t := range filter.Apply(tenants) {
+				names = append(names, t.Name)
+			}
+			Expect(names).To(Equal(expected))
+		},
+		Entry("no filter", Filter{}, []string{"ou1", "du1", "du2"}),
+		Entry("kind", Filter{Kind: "deliveryunit"}, []string{"du1", "du2"}),
+		Entry("owner", Filter{Owner: "ou1"}, []string{"du1", "du2"}),
+		Entry("type", Filter{Type: "application"}, []string{"du1"}),
+		Entry("environment", Filter{Environment: "prod"}, []string{"ou1", "du2"}),
+		Entry("repo missing", Filter{RepoMissing: true}, []string{"du2"}),
+		Entry("contact email glob", Filter{ContactEmail: "*@company.com"}, []string{"ou1", "du1"}),
+		Entry("combined", Filter{Kind: "DeliveryUnit", ContactEmail: "team@*"}, []string{"du1", "du2"}),
+	)
+
+	It("rejects an invalid contact email pattern", func() {
+		Expect(Filter{ContactEmail: "[team"}.Validate()).To(HaveOccurred())
+		Expect(Filter{ContactEmail: "*@company.com"}.Validate()).To(Succeed())
+	})
+})
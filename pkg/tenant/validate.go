@@ -0,0 +1,38 @@
+package tenant
+
+import (
+	"errors"
+
+	"github.com/coreeng/core-platform/pkg/tenant"
+	"github.com/coreeng/corectl/pkg/logger"
+)
+
+// Validate validates the tenant along with all the other tenants, which it replaces the tenant of the same name of.
+// The warnings related to the tenant are logged, and the first error is returned when it is related to the tenant.
+func Validate(t *tenant.Tenant, allTenants []tenant.Tenant) error {
+	validationResult := tenant.ValidateTenants(tenantsByName(t, allTenants))
+	for _, warn := range validationResult.Warnings {
+		var tenantRelatedWarn tenant.TenantRelatedError
+		if errors.As(warn, &tenantRelatedWarn) && tenantRelatedWarn.IsRelatedToTenant(t) {
+			logger.Error().Msg(warn.Error())
+		}
+	}
+	var tenantRelatedErr tenant.TenantRelatedError
+	if len(validationResult.Errors) > 0 &&
+		errors.As(validationResult.Errors[0], &tenantRelatedErr) &&
+		tenantRelatedErr.IsRelatedToTenant(t) {
+		return tenantRelatedErr
+	}
+	return nil
+}
+
+// tenantsByName returns the tenants by name, pointing to the elements of allTenants, with t in place of
+// the tenant of the same name.
+func tenantsByName(t *tenant.Tenant, allTenants []tenant.Tenant) map[string]*tenant.Tenant {
+	tenantMap := make(map[string]*tenant.Tenant, len(allTenants)+1)
+	for i := range allTenants {
+		tenantMap[allTenants[i].Name] = &allTenants[i]
+	}
+	tenantMap[t.Name] = t
+	return tenantMap
+}
@@ -0,0 +1,34 @@
+package tenant
+
+import (
+	"github.com/coreeng/core-platform/pkg/tenant"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("tenantsByName", func() {
+	It("points to each tenant in the slice", func() {
+		tenants := []tenant.Tenant{
+			{Name: "ou-alpha"},
+			{Name: "ou-beta"},
+		}
+
+		tenantMap := tenantsByName(&tenant.Tenant{Name: "du"}, tenants)
+
+		Expect(tenantMap).To(HaveLen(3))
+		Expect(tenantMap["ou-alpha"]).To(BeIdenticalTo(&tenants[0]))
+		Expect(tenantMap["ou-beta"]).To(BeIdenticalTo(&tenants[1]))
+	})
+
+	It("replaces the tenant of the same name", func() {
+		tenants := []tenant.Tenant{
+			{Name: "ou-alpha", Description: "before"},
+		}
+		updated := &tenant.Tenant{Name: "ou-alpha", Description: "after"}
+
+		tenantMap := tenantsByName(updated, tenants)
+
+		Expect(tenantMap).To(HaveLen(1))
+		Expect(tenantMap["ou-alpha"]).To(BeIdenticalTo(updated))
+	})
+})
@@ -35,106 +35,274 @@ func CreateOrUpdate(
 ) (result CreateOrUpdateResult, err error) {
 	result = CreateOrUpdateResult{}
 
-	repository, err := git.OpenAndResetRepositoryState(op.CplatformRepoPath, op.DryRun)
+	definition, err := yaml.Marshal(op.Tenant)
 	if err != nil {
-		return result, fmt.Errorf("couldn't open cplatform repository: %v", err)
+		return result, err
 	}
+	result.PRUrl, err = raisePR(&prOp{
+		cplatformRepoPath: op.CplatformRepoPath,
+		branchName:        op.BranchName,
+		commitMessage:     op.CommitMessage,
+		prName:            op.PRName,
+		prBody:            op.PRBody,
+		gitAuth:           op.GitAuth,
+		dryRun:            op.DryRun,
+	}, githubClient, func(repository *git.LocalRepository) error {
+		logger.Debug().With(
+			// TODO: add public method to render Tenant in Core Platform
+			//       so we can log it here when dry-running
+			zap.String("repo", op.CplatformRepoPath),
+			zap.Bool("dry_run", op.DryRun),
+			zap.String("definition", string(definition))).
+			Msg("writing tenant definition to cplatform repo")
+		var relativeFilepath string
+		if !op.DryRun {
+			if err := tenant.CreateOrUpdate(tenant.CreateOrUpdateOp{
+				Tenant:      op.Tenant,
+				OwnerTenant: op.OwnerTenant,
+				TenantsDir:  configpath.GetCorectlCPlatformDir("tenants"),
+			}); err != nil {
+				return err
+			}
+			var err error
+			relativeFilepath, err = RelativeSavedPath(op.CplatformRepoPath, op.Tenant)
+			if err != nil {
+				return err
+			}
+		} else {
+			var err error
+			relativeFilepath, err = approximateTenantFilePathForDryRun(op)
+			if err != nil {
+				return err
+			}
+		}
+		return repository.AddFiles(relativeFilepath)
+	})
+	return result, err
+}
 
-	if err = repository.CheckoutBranch(&git.CheckoutOp{
-		BranchName:      op.BranchName,
-		CreateIfMissing: true,
-	}); err != nil {
+type DeleteOp struct {
+	Tenants           []*tenant.Tenant
+	CplatformRepoPath string
+	BranchName        string
+	CommitMessage     string
+	PRName            string
+	PRBody            string
+	GitAuth           git.AuthMethod
+	DryRun            bool
+}
+
+type DeleteResult struct {
+	PRUrl string
+	// Files are the definitions removed, relative to the cplatform repository
+	Files []string
+}
+
+// Delete removes the definitions of the tenants from the cplatform repository, and raises a PR with the removal.
+func Delete(
+	op *DeleteOp,
+	githubClient *github.Client,
+) (result DeleteResult, err error) {
+	result = DeleteResult{}
+	result.Files, err = DefinitionFiles(op.CplatformRepoPath, op.Tenants)
+	if err != nil {
 		return result, err
 	}
-	defer func() {
-		_ = repository.CheckoutBranch(&git.CheckoutOp{BranchName: git.MainBranch})
-	}()
 
-	definition, err := yaml.Marshal(op.Tenant)
+	result.PRUrl, err = raisePR(&prOp{
+		cplatformRepoPath: op.CplatformRepoPath,
+		branchName:        op.BranchName,
+		commitMessage:     op.CommitMessage,
+		prName:            op.PRName,
+		prBody:            op.PRBody,
+		gitAuth:           op.GitAuth,
+		dryRun:            op.DryRun,
+	}, githubClient, func(repository *git.LocalRepository) error {
+		logger.Debug().With(
+			zap.String("repo", op.CplatformRepoPath),
+			zap.Bool("dry_run", op.DryRun),
+			zap.Strings("files", result.Files)).
+			Msg("removing tenant definitions from cplatform repo")
+		return repository.RemoveFiles(result.Files...)
+	})
+	return result, err
+}
+
+type MoveOp struct {
+	// Tenant is the moved tenant, which isn't saved yet
+	Tenant *tenant.Tenant
+	// From is the tenant before the move, as saved in the cplatform repository
+	From              *tenant.Tenant
+	OwnerTenant       *tenant.Tenant
+	CplatformRepoPath string
+	BranchName        string
+	CommitMessage     string
+	PRName            string
+	PRBody            string
+	GitAuth           git.AuthMethod
+	DryRun            bool
+}
+
+// Move saves the tenant under its new owner and removes its previous definition,
+// raising a single PR with both changes.
+func Move(
+	op *MoveOp,
+	githubClient *github.Client,
+) (result CreateOrUpdateResult, err error) {
+	result = CreateOrUpdateResult{}
+	previousFiles, err := DefinitionFiles(op.CplatformRepoPath, []*tenant.Tenant{op.From})
 	if err != nil {
 		return result, err
 	}
-	logger.Debug().With(
-		// TODO: add public method to render Tenant in Core Platform
-		//       so we can log it here when dry-running
-		zap.String("repo", op.CplatformRepoPath),
-		zap.Bool("dry_run", op.DryRun),
-		zap.String("definition", string(definition))).
-		Msg("writing tenant definition to cplatform repo")
-	var relativeFilepath string
-	if !op.DryRun {
-		if err = tenant.CreateOrUpdate(tenant.CreateOrUpdateOp{
-			Tenant:      op.Tenant,
-			OwnerTenant: op.OwnerTenant,
-			TenantsDir:  configpath.GetCorectlCPlatformDir("tenants"),
-		}); err != nil {
-			return result, err
-		}
-		repoPath, err := filepath.EvalSymlinks(op.CplatformRepoPath)
-		if err != nil {
-			return result, err
+
+	result.PRUrl, err = raisePR(&prOp{
+		cplatformRepoPath: op.CplatformRepoPath,
+		branchName:        op.BranchName,
+		commitMessage:     op.CommitMessage,
+		prName:            op.PRName,
+		prBody:            op.PRBody,
+		gitAuth:           op.GitAuth,
+		dryRun:            op.DryRun,
+	}, githubClient, func(repository *git.LocalRepository) error {
+		logger.Debug().With(
+			zap.String("repo", op.CplatformRepoPath),
+			zap.Bool("dry_run", op.DryRun),
+			zap.String("from", previousFiles[0]),
+			zap.String("owner", op.OwnerTenant.Name)).
+			Msg("moving tenant definition in cplatform repo")
+		if err := repository.RemoveFiles(previousFiles...); err != nil {
+			return err
 		}
-		savedPath, err := filepath.EvalSymlinks(*op.Tenant.SavedPath())
-		if err != nil {
-			return result, err
+		var relativeFilepath string
+		if !op.DryRun {
+			if err := tenant.CreateOrUpdate(tenant.CreateOrUpdateOp{
+				Tenant:      op.Tenant,
+				OwnerTenant: op.OwnerTenant,
+				TenantsDir:  configpath.GetCorectlCPlatformDir("tenants"),
+			}); err != nil {
+				return err
+			}
+			var err error
+			relativeFilepath, err = RelativeSavedPath(op.CplatformRepoPath, op.Tenant)
+			if err != nil {
+				return err
+			}
+		} else {
+			var err error
+			relativeFilepath, err = FilePath(op.Tenant)
+			if err != nil {
+				return err
+			}
 		}
-		relativeFilepath, err = filepath.Rel(repoPath, savedPath)
-		if err != nil {
-			return result, err
+		return repository.AddFiles(relativeFilepath)
+	})
+	return result, err
+}
+
+// DefinitionFiles returns the files the tenants are defined in, relative to the cplatform repository.
+func DefinitionFiles(cplatformRepoPath string, tenants []*tenant.Tenant) ([]string, error) {
+	files := make([]string, len(tenants))
+	for i, t := range tenants {
+		if t.SavedPath() == nil {
+			return nil, fmt.Errorf("tenant %s is not saved in the cplatform repository", t.Name)
 		}
-	} else {
-		relativeFilepath, err = approximateTenantFilePathForDryRun(op)
+		file, err := RelativeSavedPath(cplatformRepoPath, t)
 		if err != nil {
-			return result, err
+			return nil, err
 		}
+		files[i] = file
 	}
+	return files, nil
+}
 
-	if err = repository.AddFiles(relativeFilepath); err != nil {
-		return result, err
+// RelativeSavedPath returns the path the tenant is saved at, relative to the cplatform repository.
+func RelativeSavedPath(cplatformRepoPath string, t *tenant.Tenant) (string, error) {
+	repoPath, err := filepath.EvalSymlinks(cplatformRepoPath)
+	if err != nil {
+		return "", err
 	}
-	if err = repository.Commit(&git.CommitOp{Message: op.CommitMessage}); err != nil {
-		return result, err
+	savedPath, err := filepath.EvalSymlinks(*t.SavedPath())
+	if err != nil {
+		return "", err
+	}
+	return filepath.Rel(repoPath, savedPath)
+}
+
+type prOp struct {
+	cplatformRepoPath string
+	branchName        string
+	commitMessage     string
+	prName            string
+	prBody            string
+	gitAuth           git.AuthMethod
+	dryRun            bool
+}
+
+// raisePR checks out a branch of the cplatform repository, stages the changes made by change,
+// then commits and pushes them, and raises a PR with them, returning its url.
+func raisePR(
+	op *prOp,
+	githubClient *github.Client,
+	change func(repository *git.LocalRepository) error,
+) (string, error) {
+	repository, err := git.OpenAndResetRepositoryState(op.cplatformRepoPath, op.dryRun)
+	if err != nil {
+		return "", fmt.Errorf("couldn't open cplatform repository: %v", err)
+	}
+
+	if err = repository.CheckoutBranch(&git.CheckoutOp{
+		BranchName:      op.branchName,
+		CreateIfMissing: true,
+	}); err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = repository.CheckoutBranch(&git.CheckoutOp{BranchName: git.MainBranch})
+	}()
+
+	if err = change(repository); err != nil {
+		return "", err
+	}
+	if err = repository.Commit(&git.CommitOp{Message: op.commitMessage}); err != nil {
+		return "", err
 	}
 	if err = repository.Push(git.PushOp{
-		Auth:       op.GitAuth,
-		BranchName: op.BranchName,
+		Auth:       op.gitAuth,
+		BranchName: op.branchName,
 	}); err != nil {
-		return result, err
+		return "", err
 	}
 
 	fullname, err := git.DeriveRepositoryFullname(repository)
 	if err != nil {
-		return result, err
+		return "", err
 	}
 
+	prBody := op.prBody
+	if prBody == "" {
+		prBody = op.prName
+	}
 	pullRequest, err := git.CreateGitHubPR(
 		githubClient,
-		op.PRName,
-		op.PRName,
-		op.BranchName,
+		op.prName,
+		prBody,
+		op.branchName,
 		fullname.Name(),
 		fullname.Organization(),
-		op.DryRun,
+		op.dryRun,
 	)
-
 	if err != nil {
-		return result, err
+		return "", err
 	}
-
-	result.PRUrl = pullRequest.GetHTMLURL()
-	return result, nil
+	return pullRequest.GetHTMLURL(), nil
 }
 
 func approximateTenantFilePathForDryRun(op *CreateOrUpdateOp) (string, error) {
 	// Best-effort path approximation used only for dry-run git operations.
 	// Matches layout used by core-platform: TenantsDir is repo/tenants, so paths are relative to repo root.
-	switch op.Tenant.Kind {
-	case "OrgUnit":
-		return filepath.Join("tenants", op.Tenant.Name+".ou.yaml"), nil
-	case "DeliveryUnit":
-		// DeliveryUnit always has an owner (required by ADR).
-		return filepath.Join("tenants", op.Tenant.Owner, op.Tenant.Name+".du.yaml"), nil
-	default:
+	path, err := FilePath(op.Tenant)
+	if err != nil {
 		return "", fmt.Errorf("unknown tenant kind for dry-run: %s", op.Tenant.Kind)
 	}
+	return path, nil
 }
@@ -12,10 +12,12 @@ import (
 	"github.com/coreeng/corectl/pkg/testutil/httpmock"
 	"github.com/coreeng/corectl/testdata"
 	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
 	"github.com/google/go-github/v60/github"
 	"github.com/migueleliasweb/go-github-mock/src/mock"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"gopkg.in/yaml.v3"
 )
 
 var _ = Describe("Create or Update", func() {
@@ -46,6 +48,7 @@ cloudAccess: []
 		branchName          string
 		commitMsg           string
 		newPrName           string
+		newPrBody           string
 		newPrHtmlUrl        string
 		createPrCapture     *httpmock.HttpCaptureHandler[github.NewPullRequest]
 		githubClient        *github.Client
@@ -88,6 +91,7 @@ cloudAccess: []
 		branchName = "new-tenant"
 		commitMsg = "New tenant create msg"
 		newPrName = "New PR"
+		newPrBody = "New PR body"
 		newPrHtmlUrl = "https://github.com/org/repo/pull/1"
 		createPrCapture = httpmock.NewCaptureHandler[github.NewPullRequest](
 			&github.PullRequest{
@@ -114,6 +118,7 @@ cloudAccess: []
 					BranchName:        branchName,
 					CommitMessage:     commitMsg,
 					PRName:            newPrName,
+					PRBody:            newPrBody,
 				},
 				githubClient,
 			)
@@ -127,6 +132,7 @@ cloudAccess: []
 			Expect(createPrCapture.Requests).To(HaveLen(1))
 			newPrRequest := createPrCapture.Requests[0]
 			Expect(*newPrRequest.Title).To(Equal(newPrName))
+			Expect(*newPrRequest.Body).To(Equal(newPrBody))
 			Expect(*newPrRequest.Head).To(Equal(branchName))
 			Expect(*newPrRequest.Base).To(Equal(git.MainBranch))
 		})
@@ -187,6 +193,373 @@ cloudAccess: []
 	})
 })
 
+var _ = Describe("raisePR", func() {
+	It("uses the PR name as body when there is no body", func() {
+		_, err := gittest.CreateTestCorectlConfig(GinkgoTB().TempDir())
+		Expect(err).NotTo(HaveOccurred())
+		_, cplatformLocalRepo, err := gittest.CreateBareAndLocalRepoFromDir(&gittest.CreateBareAndLocalRepoOp{
+			SourceDir:          testdata.CPlatformEnvsPath(),
+			TargetBareRepoDir:  GinkgoTB().TempDir(),
+			TargetLocalRepoDir: configpath.GetCorectlCPlatformDir(),
+		})
+		Expect(err).NotTo(HaveOccurred())
+		prHtmlUrl := "https://github.com/org/repo/pull/1"
+		createPrCapture := httpmock.NewCaptureHandler[github.NewPullRequest](
+			&github.PullRequest{
+				HTMLURL: &prHtmlUrl,
+			},
+		)
+		githubClient := github.NewClient(mock.NewMockedHTTPClient(
+			mock.WithRequestMatchHandler(
+				mock.PostReposPullsByOwnerByRepo,
+				createPrCapture.Func(),
+			),
+		))
+
+		_, err = raisePR(&prOp{
+			cplatformRepoPath: cplatformLocalRepo.Path(),
+			branchName:        "no-body",
+			commitMessage:     "No body",
+			prName:            "PR without body",
+		}, githubClient, func(repository *git.LocalRepository) error {
+			Expect(os.WriteFile(filepath.Join(cplatformLocalRepo.Path(), "file.txt"), []byte("content"), 0o644)).To(Succeed())
+			return repository.AddFiles("file.txt")
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(createPrCapture.Requests).To(HaveLen(1))
+		Expect(*createPrCapture.Requests[0].Body).To(Equal("PR without body"))
+	})
+})
+
+var _ = Describe("Delete", func() {
+	t := GinkgoTB()
+
+	var (
+		cplatformServerRepo *gittest.BareRepository
+		cplatformLocalRepo  *git.LocalRepository
+		mainBranchRefName   plumbing.ReferenceName
+		originalMainRef     *plumbing.Reference
+		parentTenant        *tenant.Tenant
+		deliveryUnit        *tenant.Tenant
+		prHtmlUrl           string
+		createPrCapture     *httpmock.HttpCaptureHandler[github.NewPullRequest]
+		githubClient        *github.Client
+	)
+	BeforeEach(OncePerOrdered, func() {
+		var err error
+		_, err = gittest.CreateTestCorectlConfig(t.TempDir())
+		Expect(err).NotTo(HaveOccurred())
+
+		cplatformServerRepo, cplatformLocalRepo, err = gittest.CreateBareAndLocalRepoFromDir(&gittest.CreateBareAndLocalRepoOp{
+			SourceDir:          testdata.CPlatformEnvsPath(),
+			TargetBareRepoDir:  t.TempDir(),
+			TargetLocalRepoDir: configpath.GetCorectlCPlatformDir(),
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		parentTenant, err = tenant.FindByName(configpath.GetCorectlCPlatformDir("tenants"), "parent")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(parentTenant).NotTo(BeNil())
+		deliveryUnit, err = tenant.FindByName(configpath.GetCorectlCPlatformDir("tenants"), "default-tenant")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(deliveryUnit).NotTo(BeNil())
+
+		mainBranchRefName = plumbing.NewBranchReferenceName(git.MainBranch)
+		originalMainRef, err = cplatformLocalRepo.Repository().Reference(mainBranchRefName, true)
+		Expect(err).NotTo(HaveOccurred())
+
+		prHtmlUrl = "https://github.com/org/repo/pull/2"
+		createPrCapture = httpmock.NewCaptureHandler[github.NewPullRequest](
+			&github.PullRequest{
+				HTMLURL: &prHtmlUrl,
+			},
+		)
+		githubClient = github.NewClient(mock.NewMockedHTTPClient(
+			mock.WithRequestMatchHandler(
+				mock.PostReposPullsByOwnerByRepo,
+				createPrCapture.Func(),
+			),
+		))
+	})
+
+	assertDeleted := func(branchName string, commitMsg string, files []string) {
+		It("leave local repository clean on the main branch", func() {
+			localChangesPresent, err := cplatformLocalRepo.IsLocalChangesPresent()
+			if Expect(err).NotTo(HaveOccurred()) {
+				Expect(localChangesPresent).To(BeFalse())
+			}
+			currentBranch, err := cplatformLocalRepo.CurrentBranch()
+			if Expect(err).NotTo(HaveOccurred()) {
+				Expect(currentBranch).To(Equal(git.MainBranch))
+			}
+			currentMainRef, err := cplatformLocalRepo.Repository().Reference(mainBranchRefName, true)
+			if Expect(err).NotTo(HaveOccurred()) {
+				Expect(currentMainRef).To(Equal(originalMainRef))
+			}
+		})
+		It("pushes all the changes to the remote repository", func() {
+			cplatformServerRepo.AssertInSyncWith(cplatformLocalRepo)
+		})
+		It("creates a commit removing the definitions", func() {
+			branchNameRef, err := cplatformLocalRepo.Repository().Reference(plumbing.NewBranchReferenceName(branchName), false)
+			Expect(err).NotTo(HaveOccurred())
+			fromHash := originalMainRef.Hash()
+			cplatformServerRepo.AssertCommits(gittest.AssertCommitOp{
+				From: &fromHash,
+				To:   branchNameRef.Hash(),
+				ExpectedCommits: []gittest.ExpectedCommit{
+					{
+						Message:      commitMsg,
+						ChangedFiles: files,
+					},
+				},
+			})
+		})
+		It("removes the definitions from the branch", func() {
+			Expect(cplatformLocalRepo.CheckoutBranch(&git.CheckoutOp{BranchName: branchName})).To(Succeed())
+			for _, file := range files {
+				_, err := os.Stat(filepath.Join(cplatformLocalRepo.Path(), file))
+				Expect(err).To(MatchError(os.ErrNotExist))
+			}
+		})
+	}
+
+	When("deleting a delivery unit", Ordered, func() {
+		var deleteResult DeleteResult
+		BeforeAll(func() {
+			var err error
+			deleteResult, err = Delete(
+				&DeleteOp{
+					Tenants:           []*tenant.Tenant{deliveryUnit},
+					CplatformRepoPath: cplatformLocalRepo.Path(),
+					BranchName:        "delete-tenant-default-tenant",
+					CommitMessage:     "Delete tenant default-tenant",
+					PRName:            "Delete tenant default-tenant",
+					PRBody:            "Deletes tenant 'default-tenant'",
+				},
+				githubClient,
+			)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("returns the PR url and the files removed", func() {
+			Expect(deleteResult.PRUrl).To(Equal(prHtmlUrl))
+			Expect(deleteResult.Files).To(Equal([]string{filepath.Join("tenants", "tenants", "parent", "default-tenant.du.yaml")}))
+		})
+		It("called create PR correctly", func() {
+			Expect(createPrCapture.Requests).To(HaveLen(1))
+			newPrRequest := createPrCapture.Requests[0]
+			Expect(*newPrRequest.Title).To(Equal("Delete tenant default-tenant"))
+			Expect(*newPrRequest.Body).To(Equal("Deletes tenant 'default-tenant'"))
+			Expect(*newPrRequest.Head).To(Equal("delete-tenant-default-tenant"))
+			Expect(*newPrRequest.Base).To(Equal(git.MainBranch))
+		})
+		assertDeleted(
+			"delete-tenant-default-tenant",
+			"Delete tenant default-tenant",
+			[]string{"./tenants/tenants/parent/default-tenant.du.yaml"},
+		)
+	})
+
+	When("deleting an org unit with its delivery units", Ordered, func() {
+		var deleteResult DeleteResult
+		BeforeAll(func() {
+			var err error
+			deleteResult, err = Delete(
+				&DeleteOp{
+					Tenants:           []*tenant.Tenant{deliveryUnit, parentTenant},
+					CplatformRepoPath: cplatformLocalRepo.Path(),
+					BranchName:        "delete-tenant-parent",
+					CommitMessage:     "Delete tenant parent",
+					PRName:            "Delete tenant parent",
+				},
+				githubClient,
+			)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("returns the PR url and the files removed", func() {
+			Expect(deleteResult.PRUrl).To(Equal(prHtmlUrl))
+			Expect(deleteResult.Files).To(Equal([]string{
+				filepath.Join("tenants", "tenants", "parent", "default-tenant.du.yaml"),
+				filepath.Join("tenants", "tenants", "parent.ou.yaml"),
+			}))
+		})
+		It("creates a single PR", func() {
+			Expect(createPrCapture.Requests).To(HaveLen(1))
+			Expect(*createPrCapture.Requests[0].Head).To(Equal("delete-tenant-parent"))
+		})
+		assertDeleted(
+			"delete-tenant-parent",
+			"Delete tenant parent",
+			[]string{
+				"./tenants/tenants/parent/default-tenant.du.yaml",
+				"./tenants/tenants/parent.ou.yaml",
+			},
+		)
+	})
+
+	When("dry-running", Ordered, func() {
+		var deleteResult DeleteResult
+		BeforeAll(func() {
+			var err error
+			deleteResult, err = Delete(
+				&DeleteOp{
+					Tenants:           []*tenant.Tenant{deliveryUnit},
+					CplatformRepoPath: cplatformLocalRepo.Path(),
+					BranchName:        "delete-tenant-default-tenant",
+					CommitMessage:     "Delete tenant default-tenant",
+					PRName:            "Delete tenant default-tenant",
+					DryRun:            true,
+				},
+				githubClient,
+			)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("returns the files which would be removed", func() {
+			Expect(deleteResult.Files).To(Equal([]string{filepath.Join("tenants", "tenants", "parent", "default-tenant.du.yaml")}))
+		})
+		It("leaves the definitions and creates no PR", func() {
+			Expect(createPrCapture.Requests).To(BeEmpty())
+			_, err := os.Stat(*deliveryUnit.SavedPath())
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
+})
+
+var _ = Describe("Move", Ordered, func() {
+	const otherOrgUnitContent = `---
+name: other
+kind: OrgUnit
+description: "Other org unit"
+contactEmail: other@prod.domain
+environments:
+  - dev
+adminGroup: other-admin-group@prod.domain
+readonlyGroup: other-readonly-group@prod.domain
+cloudAccess: []
+`
+	t := GinkgoTB()
+
+	var (
+		cplatformServerRepo *gittest.BareRepository
+		cplatformLocalRepo  *git.LocalRepository
+		originalMainRef     *plumbing.Reference
+		deliveryUnit        *tenant.Tenant
+		otherTenant         *tenant.Tenant
+		moved               *tenant.Tenant
+		branchName          string
+		commitMsg           string
+		prHtmlUrl           string
+		createPrCapture     *httpmock.HttpCaptureHandler[github.NewPullRequest]
+		moveResult          CreateOrUpdateResult
+	)
+	BeforeAll(func() {
+		var err error
+		_, err = gittest.CreateTestCorectlConfig(t.TempDir())
+		Expect(err).NotTo(HaveOccurred())
+
+		sourceDir := t.TempDir()
+		Expect(os.CopyFS(sourceDir, os.DirFS(testdata.CPlatformEnvsPath()))).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(sourceDir, "tenants", "tenants", "other.ou.yaml"), []byte(otherOrgUnitContent), 0o644)).To(Succeed())
+		cplatformServerRepo, cplatformLocalRepo, err = gittest.CreateBareAndLocalRepoFromDir(&gittest.CreateBareAndLocalRepoOp{
+			SourceDir:          sourceDir,
+			TargetBareRepoDir:  t.TempDir(),
+			TargetLocalRepoDir: configpath.GetCorectlCPlatformDir(),
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		deliveryUnit, err = tenant.FindByName(configpath.GetCorectlCPlatformDir("tenants"), "default-tenant")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(deliveryUnit).NotTo(BeNil())
+		otherTenant, err = tenant.FindByName(configpath.GetCorectlCPlatformDir("tenants"), "other")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(otherTenant).NotTo(BeNil())
+
+		// the moved tenant is decoded from the definition, so it isn't saved yet
+		definition, err := yaml.Marshal(deliveryUnit)
+		Expect(err).NotTo(HaveOccurred())
+		moved = &tenant.Tenant{}
+		Expect(yaml.Unmarshal(definition, moved)).To(Succeed())
+		moved.Owner = otherTenant.Name
+
+		originalMainRef, err = cplatformLocalRepo.Repository().Reference(plumbing.NewBranchReferenceName(git.MainBranch), true)
+		Expect(err).NotTo(HaveOccurred())
+
+		branchName = "move-tenant-default-tenant-to-other"
+		commitMsg = "Move delivery unit default-tenant to org unit other"
+		prHtmlUrl = "https://github.com/org/repo/pull/3"
+		createPrCapture = httpmock.NewCaptureHandler[github.NewPullRequest](
+			&github.PullRequest{
+				HTMLURL: &prHtmlUrl,
+			},
+		)
+		githubClient := github.NewClient(mock.NewMockedHTTPClient(
+			mock.WithRequestMatchHandler(
+				mock.PostReposPullsByOwnerByRepo,
+				createPrCapture.Func(),
+			),
+		))
+
+		moveResult, err = Move(
+			&MoveOp{
+				Tenant:            moved,
+				From:              deliveryUnit,
+				OwnerTenant:       otherTenant,
+				CplatformRepoPath: cplatformLocalRepo.Path(),
+				BranchName:        branchName,
+				CommitMessage:     commitMsg,
+				PRName:            commitMsg,
+				PRBody:            "Moves delivery unit 'default-tenant' from org unit 'parent' to 'other'",
+			},
+			githubClient,
+		)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("creates exactly one PR", func() {
+		Expect(moveResult.PRUrl).To(Equal(prHtmlUrl))
+		Expect(createPrCapture.Requests).To(HaveLen(1))
+		newPrRequest := createPrCapture.Requests[0]
+		Expect(*newPrRequest.Title).To(Equal(commitMsg))
+		Expect(*newPrRequest.Body).To(Equal("Moves delivery unit 'default-tenant' from org unit 'parent' to 'other'"))
+		Expect(*newPrRequest.Head).To(Equal(branchName))
+		Expect(*newPrRequest.Base).To(Equal(git.MainBranch))
+	})
+	It("pushes all the changes to the remote repository", func() {
+		cplatformServerRepo.AssertInSyncWith(cplatformLocalRepo)
+	})
+	It("creates a single commit deleting the old definition and creating the new one", func() {
+		branchNameRef, err := cplatformLocalRepo.Repository().Reference(plumbing.NewBranchReferenceName(branchName), false)
+		Expect(err).NotTo(HaveOccurred())
+		commit, err := cplatformLocalRepo.Repository().CommitObject(branchNameRef.Hash())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(commit.Message).To(Equal(commitMsg))
+		Expect(commit.ParentHashes).To(Equal([]plumbing.Hash{originalMainRef.Hash()}))
+
+		// git may report the change as a rename, so the files of the commit are checked instead of its stats
+		tree, err := commit.Tree()
+		Expect(err).NotTo(HaveOccurred())
+		_, err = tree.File("tenants/tenants/parent/default-tenant.du.yaml")
+		Expect(err).To(MatchError(object.ErrFileNotFound))
+		_, err = tree.File("tenants/tenants/other/default-tenant.du.yaml")
+		Expect(err).NotTo(HaveOccurred())
+	})
+	It("moves the definition under the new org unit with the owner updated", func() {
+		Expect(cplatformLocalRepo.CheckoutBranch(&git.CheckoutOp{BranchName: branchName})).To(Succeed())
+		_, err := os.Stat(filepath.Join(cplatformLocalRepo.Path(), "tenants", "tenants", "parent", "default-tenant.du.yaml"))
+		Expect(err).To(MatchError(os.ErrNotExist))
+
+		content, err := os.ReadFile(filepath.Join(cplatformLocalRepo.Path(), "tenants", "tenants", "other", "default-tenant.du.yaml"))
+		Expect(err).NotTo(HaveOccurred())
+		movedDefinition := tenant.Tenant{}
+		Expect(yaml.Unmarshal(content, &movedDefinition)).To(Succeed())
+		Expect(movedDefinition.Name).To(Equal("default-tenant"))
+		Expect(movedDefinition.Owner).To(Equal("other"))
+	})
+})
+
 var _ = Describe("approximateTenantFilePathForDryRun", func() {
 	It("returns tenants/<name>.ou.yaml for OrgUnit", func() {
 		op := &CreateOrUpdateOp{
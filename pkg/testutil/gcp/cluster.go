@@ -4,12 +4,15 @@ import (
 	"context"
 	"fmt"
 	"net"
+	"strings"
 
 	container "cloud.google.com/go/container/apiv1"
 	"cloud.google.com/go/container/apiv1/containerpb"
 	"google.golang.org/api/option"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
 )
 
 type mockClusterServer struct {
@@ -54,10 +57,45 @@ func NewClusterMockClient() (*container.ClusterManagerClient, error) {
 	return client, nil
 }
 
+// MissingCluster is the name of a cluster the mock server doesn't find
+const MissingCluster = "missing"
+
 func (f *mockClusterServer) GetCluster(ctx context.Context, req *containerpb.GetClusterRequest) (*containerpb.Cluster, error) {
+	if strings.HasSuffix(req.GetName(), "/clusters/"+MissingCluster) {
+		return nil, status.Errorf(codes.NotFound, "cluster %s not found", req.GetName())
+	}
 	resp := &containerpb.Cluster{
-		Name:      "gcp-predev-1234",
-		Locations: []string{"us-west-2"},
+		Name:                 "gcp-predev-1234",
+		Locations:            []string{"us-west-2"},
+		Endpoint:             "10.0.0.2",
+		Status:               containerpb.Cluster_RUNNING,
+		CurrentMasterVersion: "1.30.5-gke.1014001",
+		ReleaseChannel:       &containerpb.ReleaseChannel{Channel: containerpb.ReleaseChannel_REGULAR},
+		MaintenancePolicy: &containerpb.MaintenancePolicy{
+			Window: &containerpb.MaintenanceWindow{
+				Policy: &containerpb.MaintenanceWindow_DailyMaintenanceWindow{
+					DailyMaintenanceWindow: &containerpb.DailyMaintenanceWindow{StartTime: "03:00", Duration: "PT4H0M0S"},
+				},
+			},
+		},
+		NodePools: []*containerpb.NodePool{
+			{
+				Name:        "default",
+				Status:      containerpb.NodePool_RUNNING,
+				Version:     "1.30.5-gke.1014001",
+				Autoscaling: &containerpb.NodePoolAutoscaling{Enabled: true, MinNodeCount: 1, MaxNodeCount: 3},
+			},
+			{
+				Name:    "system",
+				Status:  containerpb.NodePool_RECONCILING,
+				Version: "1.29.8-gke.1211000",
+			},
+		},
+		ControlPlaneEndpointsConfig: &containerpb.ControlPlaneEndpointsConfig{
+			DnsEndpointConfig: &containerpb.ControlPlaneEndpointsConfig_DNSEndpointConfig{
+				Endpoint: "gke-1234.europe-west2.gke.goog",
+			},
+		},
 	}
 	return resp, nil
 }
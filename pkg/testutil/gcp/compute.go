@@ -0,0 +1,38 @@
+package gcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"google.golang.org/api/compute/v1"
+	"google.golang.org/api/option"
+)
+
+// NewComputeMockService returns a compute service knowing only about the given instances,
+// identified by their `projects/<project>/zones/<zone>/instances/<name>` path.
+func NewComputeMockService(instances ...string) (*compute.Service, error) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, instance := range instances {
+			if strings.HasSuffix(r.URL.Path, "/"+instance) {
+				name := instance[strings.LastIndex(instance, "/")+1:]
+				_ = json.NewEncoder(w).Encode(&compute.Instance{Name: name, Status: "RUNNING"})
+				return
+			}
+		}
+		http.Error(w, `{"error": {"code": 404, "message": "not found"}}`, http.StatusNotFound)
+	}))
+
+	s, err := compute.NewService(context.Background(),
+		option.WithEndpoint(srv.URL),
+		option.WithHTTPClient(srv.Client()),
+		option.WithoutAuthentication(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("create mock compute service for test: %w", err)
+	}
+	return s, nil
+}
@@ -0,0 +1,59 @@
+package gcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+
+	"google.golang.org/api/cloudresourcemanager/v1"
+	"google.golang.org/api/option"
+)
+
+// NewResourceManagerMockService returns a resource manager service granting the caller only the given permissions,
+// on any project.
+func NewResourceManagerMockService(granted ...string) (*cloudresourcemanager.Service, error) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req cloudresourcemanager.TestIamPermissionsRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, `{"error": {"code": 400, "message": "invalid request"}}`, http.StatusBadRequest)
+			return
+		}
+		resp := cloudresourcemanager.TestIamPermissionsResponse{}
+		for _, permission := range req.Permissions {
+			if slices.Contains(granted, permission) {
+				resp.Permissions = append(resp.Permissions, permission)
+			}
+		}
+		_ = json.NewEncoder(w).Encode(&resp)
+	}))
+
+	s, err := cloudresourcemanager.NewService(context.Background(),
+		option.WithEndpoint(srv.URL),
+		option.WithHTTPClient(srv.Client()),
+		option.WithoutAuthentication(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("create mock resource manager service for test: %w", err)
+	}
+	return s, nil
+}
+
+// NewResourceManagerForbiddenMockService returns a resource manager service denying the caller access to any project.
+func NewResourceManagerForbiddenMockService() (*cloudresourcemanager.Service, error) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error": {"code": 403, "message": "permission denied"}}`, http.StatusForbidden)
+	}))
+
+	s, err := cloudresourcemanager.NewService(context.Background(),
+		option.WithEndpoint(srv.URL),
+		option.WithHTTPClient(srv.Client()),
+		option.WithoutAuthentication(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("create mock resource manager service for test: %w", err)
+	}
+	return s, nil
+}